@@ -2,28 +2,87 @@ package main
 
 import (
 	// --- Standard Lib ---
+	"context"
+	"errors"
+	"flag"
 	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
 
 	// --- Internal Lib ---
+	"maelstrom-broadcast/internal/config"
 	"maelstrom-broadcast/internal/gossip"
+	"maelstrom-broadcast/internal/logging"
 
 	// --- Third Party ---
 	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
 )
 
 func main() {
+	// GOSSIP_LOG_LEVEL, GOSSIP_LOG_SAMPLE and GOSSIP_LOG_FORMAT tune logging
+	logCfg, err := logging.FromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	logger := logging.New(os.Stderr, logCfg)
+
+	// The Maelstrom library logs every message sent and received through
+	// the standard logger; demote that to debug records of its own component
+	// so GOSSIP_LOG_LEVEL governs it like everything else
+	log.SetFlags(0)
+	log.SetOutput(slog.NewLogLogger(logger.With(logging.ComponentKey, "maelstrom").Handler(), slog.LevelDebug).Writer())
+	fatal := func(err error) {
+		logger.Error("fatal", "err", err)
+		os.Exit(1)
+	}
+
+	// Timing, batching, topology and retry settings come from flags,
+	// GOSSIP_* variables and the optional file named by GOSSIP_CONFIG
+	cfg, err := config.Load(os.Args[1:], os.Getenv)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fatal(err)
+	}
+	logger.Info("config loaded", "config", cfg)
+
 	n := maelstrom.NewNode()
-	s := gossip.NewServer(n)
+	s := gossip.NewServer(n, cfg.Options()...)
+	if err := cfg.Apply(s); err != nil {
+		fatal(err)
+	}
+	s.Logger = logger
+	s.Register(n)
 
-	n.Handle("echo", s.HandleEcho)
-	n.Handle("generate", s.HandleGenerate)
-	n.Handle("broadcast", s.HandleBroadcast)
-	n.Handle("read", s.HandleRead)
-	n.Handle("topology", s.HandleTopology)
-	n.Handle("delta", s.HandleDelta)
-	n.Handle("delta_ok", s.HandleDeltaOK)
+	// Gossip until stdin closes or a signal arrives; a failing gossip loop
+	// takes the node down rather than leaving it silently partitioned
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	if err := s.Start(ctx); err != nil {
+		fatal(err)
+	}
+	go func() {
+		<-s.Done()
+		if err := s.Err(); err != nil {
+			fatal(err)
+		}
+		if ctx.Err() != nil {
+			// Interrupted: flush what is queued, then exit as the signal would
+			if err := s.Stop(); err != nil {
+				fatal(err)
+			}
+			os.Exit(0)
+		}
+	}()
 
-	if err := n.Run(); err != nil {
-		log.Fatal(err)
+	runErr := n.Run()
+	if err := s.Stop(); err != nil {
+		fatal(err)
+	}
+	if runErr != nil {
+		fatal(runErr)
 	}
 }
@@ -0,0 +1,208 @@
+// Package antientropy summarizes integer sets as hashed range digests so two
+// nodes can find where their sets differ without exchanging the sets.
+//
+// Values are grouped into fixed-width buckets ([lo, lo+width)), and buckets
+// into a Merkle tree of range nodes: level 0 holds the buckets and each node
+// above spans Fanout nodes of the level below. Every node is summarized by
+// its size and the XOR of its members' hashes, and the root is the XOR over
+// the whole set. Equal roots mean the sets are (almost surely) equal;
+// otherwise the two sides descend from the top level, expanding only the
+// nodes that differ, until the differing buckets are pinpointed, so a single
+// missing value costs one path down the tree and one bucket, not the whole set.
+package antientropy
+
+import (
+	"math"
+	"slices"
+)
+
+// DefaultBucketWidth is the number of consecutive values covered by a bucket.
+const DefaultBucketWidth = 64
+
+// Fanout is how many nodes of the level below a tree node spans.
+const Fanout = 16
+
+// Levels is the height of the tree; level Levels-1 is the top.
+const Levels = 4
+
+// hashMask keeps hashes within 53 bits so they survive JSON round trips
+// through tools that decode numbers as doubles.
+const hashMask = 1<<53 - 1
+
+// Summary describes one tree node: the values in [Lo, Lo+span), where span
+// depends on the node's level.
+type Summary struct {
+	Lo    int
+	Count int
+	Hash  uint64
+}
+
+// Digest is the Merkle tree of range summaries for one snapshot of a set.
+type Digest struct {
+	width  int
+	levels [Levels]map[int]*Summary
+	root   uint64
+	count  int
+}
+
+// Build summarizes values using buckets of the given width.
+func Build(values []int, width int) *Digest {
+	if width < 1 {
+		width = DefaultBucketWidth
+	}
+	d := &Digest{width: width}
+	for level := range d.levels {
+		d.levels[level] = make(map[int]*Summary)
+	}
+	for _, v := range values {
+		h := hash(v)
+		for level, nodes := range d.levels {
+			lo := BucketOf(v, Span(width, level))
+			n, ok := nodes[lo]
+			if !ok {
+				n = &Summary{Lo: lo}
+				nodes[lo] = n
+			}
+			n.Count++
+			n.Hash ^= h
+		}
+		d.root ^= h
+		d.count++
+	}
+	return d
+}
+
+// Span returns how many consecutive values a node at level covers for
+// buckets of the given width, saturating rather than overflowing.
+func Span(width, level int) int {
+	span := width
+	for range level {
+		if span > math.MaxInt/Fanout {
+			return math.MaxInt
+		}
+		span *= Fanout
+	}
+	return span
+}
+
+// Width returns the bucket width the digest was built with.
+func (d *Digest) Width() int {
+	return d.width
+}
+
+// Root returns the hash of the whole set.
+func (d *Digest) Root() uint64 {
+	return d.root
+}
+
+// Count returns the size of the whole set.
+func (d *Digest) Count() int {
+	return d.count
+}
+
+// Summaries returns every non-empty bucket in ascending order.
+func (d *Digest) Summaries() []Summary {
+	return d.Level(0, nil)
+}
+
+// Level returns the non-empty nodes at level in ascending order. If within
+// is non-nil, only children of those nodes of the level above, by lower
+// bound, are returned. Returns nil for a level outside the tree.
+func (d *Digest) Level(level int, within []int) []Summary {
+	if level < 0 || level >= Levels {
+		return nil
+	}
+	out := make([]Summary, 0, len(d.levels[level]))
+	for _, n := range d.levels[level] {
+		if d.inside(level, n.Lo, within) {
+			out = append(out, *n)
+		}
+	}
+	slices.SortFunc(out, func(a, b Summary) int { return a.Lo - b.Lo })
+	return out
+}
+
+// Matches reports whether a remote set with the given root and size is
+// equal to this one.
+func (d *Digest) Matches(root uint64, count int) bool {
+	return d.root == root && d.count == count
+}
+
+// Diff returns the buckets, by lower bound, where remote differs from d,
+// including buckets only one side has. Sorted ascending.
+func (d *Digest) Diff(remote []Summary) []int {
+	return d.DiffLevel(0, remote, nil)
+}
+
+// DiffLevel returns the nodes at level, by lower bound, where remote differs
+// from d, including nodes only one side has. remote holds the other side's
+// nodes at level; if within is non-nil, both sides are compared only under
+// those nodes of the level above. Sorted ascending.
+func (d *Digest) DiffLevel(level int, remote []Summary, within []int) []int {
+	if level < 0 || level >= Levels {
+		return nil
+	}
+	nodes := d.levels[level]
+	seen := make(map[int]struct{}, len(remote))
+	var out []int
+	for _, r := range remote {
+		if !d.inside(level, r.Lo, within) {
+			continue
+		}
+		seen[r.Lo] = struct{}{}
+		if n, ok := nodes[r.Lo]; !ok || n.Count != r.Count || n.Hash != r.Hash {
+			out = append(out, r.Lo)
+		}
+	}
+	for lo := range nodes {
+		if _, ok := seen[lo]; !ok && d.inside(level, lo, within) {
+			out = append(out, lo)
+		}
+	}
+	slices.Sort(out)
+	return out
+}
+
+// inside reports whether the node at level starting at lo is a child of one
+// of the given nodes of the level above. A nil within admits every node.
+func (d *Digest) inside(level, lo int, within []int) bool {
+	if within == nil {
+		return true
+	}
+	return slices.Contains(within, BucketOf(lo, Span(d.width, level+1)))
+}
+
+// Select returns the values that fall into any of the given buckets.
+func Select(values []int, width int, buckets []int) []int {
+	want := make(map[int]struct{}, len(buckets))
+	for _, lo := range buckets {
+		want[lo] = struct{}{}
+	}
+	var out []int
+	for _, v := range values {
+		if _, ok := want[BucketOf(v, width)]; ok {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// BucketOf returns the lower bound of the bucket containing v.
+// Rounds towards negative infinity so negative values bucket correctly.
+func BucketOf(v, width int) int {
+	lo := v / width * width
+	if v < 0 && lo != v {
+		lo -= width
+	}
+	return lo
+}
+
+// hash mixes v with the SplitMix64 finalizer so nearby values produce
+// unrelated hashes and XOR-combining them does not cancel out.
+func hash(v int) uint64 {
+	x := uint64(v) + 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	x ^= x >> 31
+	return x & hashMask
+}
@@ -0,0 +1,112 @@
+package antientropy
+
+import (
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func seq(lo, hi int) []int {
+	var out []int
+	for v := lo; v < hi; v++ {
+		out = append(out, v)
+	}
+	return out
+}
+
+func TestBucketOf(t *testing.T) {
+	assert.Equal(t, 0, BucketOf(0, 10))
+	assert.Equal(t, 0, BucketOf(9, 10))
+	assert.Equal(t, 10, BucketOf(10, 10))
+	assert.Equal(t, -10, BucketOf(-1, 10))
+	assert.Equal(t, -10, BucketOf(-10, 10))
+	assert.Equal(t, -20, BucketOf(-11, 10))
+}
+
+func TestDigest_EqualSetsMatch(t *testing.T) {
+	a := Build([]int{1, 2, 3, 100}, 10)
+	b := Build([]int{100, 3, 2, 1}, 10)
+
+	assert.True(t, a.Matches(b.Root(), b.Count()))
+	assert.Empty(t, a.Diff(b.Summaries()))
+}
+
+func TestDigest_DiffFindsOnlyChangedBuckets(t *testing.T) {
+	a := Build(seq(0, 100), 10)
+	b := Build(append(seq(0, 100), 1000), 10)
+
+	missing := make([]int, 0)
+	for _, v := range seq(0, 100) {
+		if v != 42 && v != 77 {
+			missing = append(missing, v)
+		}
+	}
+	c := Build(missing, 10)
+
+	assert.False(t, a.Matches(b.Root(), b.Count()))
+	assert.Equal(t, []int{1000}, a.Diff(b.Summaries()))
+	assert.Equal(t, []int{40, 70}, a.Diff(c.Summaries()))
+	assert.Equal(t, []int{40, 70}, c.Diff(a.Summaries()))
+}
+
+func TestDigest_Summaries(t *testing.T) {
+	d := Build([]int{25, 3, 4}, 10)
+	s := d.Summaries()
+
+	assert.Len(t, s, 2)
+	assert.Equal(t, 0, s[0].Lo)
+	assert.Equal(t, 2, s[0].Count)
+	assert.Equal(t, 20, s[1].Lo)
+	assert.Equal(t, 3, d.Count())
+}
+
+func TestSelect(t *testing.T) {
+	values := []int{1, 15, 22, 37, -3}
+	assert.Equal(t, []int{15, 37}, Select(values, 10, []int{10, 30}))
+	assert.Equal(t, []int{-3}, Select(values, 10, []int{-10}))
+	assert.Empty(t, Select(values, 10, nil))
+}
+
+func TestDigest_HashFitsJSONNumbers(t *testing.T) {
+	d := Build(seq(0, 1000), DefaultBucketWidth)
+	for _, s := range d.Summaries() {
+		assert.Less(t, s.Hash, uint64(1<<53))
+	}
+	assert.Less(t, d.Root(), uint64(1<<53))
+}
+
+func TestSpan(t *testing.T) {
+	assert.Equal(t, 10, Span(10, 0))
+	assert.Equal(t, 160, Span(10, 1))
+	assert.Equal(t, 10*Fanout*Fanout*Fanout, Span(10, Levels-1))
+	assert.Equal(t, math.MaxInt, Span(math.MaxInt/2, 1))
+}
+
+func TestDigest_DescendToDifferingBuckets(t *testing.T) {
+	values := seq(0, 5000)
+	a := Build(values, 10)
+	b := Build(append(slices.Clone(values), 7000), 10)
+
+	// Walk down from the top, expanding only the nodes that differ
+	var within []int
+	for level := Levels - 1; level > 0; level-- {
+		within = a.DiffLevel(level, b.Level(level, within), within)
+		assert.Len(t, within, 1, "level %d", level)
+	}
+	assert.Equal(t, []int{7000}, a.DiffLevel(0, b.Level(0, within), within))
+}
+
+func TestDigest_LevelWithin(t *testing.T) {
+	d := Build([]int{5, 15, 170, 3000}, 10)
+
+	assert.Len(t, d.Level(1, nil), 3)
+	assert.Equal(t, []Summary{{Lo: 170, Count: 1, Hash: hash(170)}}, d.Level(0, []int{160}))
+	assert.Empty(t, d.Level(0, []int{320}))
+	assert.Nil(t, d.Level(Levels, nil))
+
+	// Nodes only the local side has still count, but only under within
+	other := Build([]int{5, 15}, 10)
+	assert.Equal(t, []int{160}, d.DiffLevel(1, other.Level(1, []int{0}), []int{0}))
+}
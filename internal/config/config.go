@@ -0,0 +1,368 @@
+// Package config loads a node's tuning parameters at startup, so gossip
+// timing, batch size, overlay shape and retry policy can be varied between
+// Maelstrom runs without a rebuild.
+//
+// Settings come from four layers, each overriding the one before: built-in
+// defaults matching NewServer, an optional JSON config file, GOSSIP_*
+// environment variables and command-line flags. Maelstrom starts nodes
+// without arguments, so flags are mostly useful from a wrapper script.
+package config
+
+import (
+	// --- Standard Lib ---
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+
+	// --- Internal Lib ---
+	"maelstrom-broadcast/internal/antientropy"
+	"maelstrom-broadcast/internal/gossip"
+	"maelstrom-broadcast/internal/idgen"
+	"maelstrom-broadcast/internal/topology"
+)
+
+// EnvFile names the config file when no -config flag is given.
+const EnvFile = "GOSSIP_CONFIG"
+
+// Duration is a time.Duration written as a string such as "50ms" in
+// config files.
+type Duration time.Duration
+
+// UnmarshalText parses a Go duration string.
+func (d *Duration) UnmarshalText(b []byte) error {
+	v, err := time.ParseDuration(string(b))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// MarshalText formats the duration as a Go duration string.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// Config holds the Server parameters that can be tuned at startup.
+type Config struct {
+	// GossipInterval is how often a gossip round runs
+	GossipInterval Duration `json:"gossip_interval"`
+
+	// GossipMax caps the values sent to one peer per round
+	GossipMax int `json:"gossip_max"`
+
+	// RetryTimeout is the first retransmission delay; RetryMax caps the
+	// exponential backoff and RetryJitter ("none", "full", "equal" or
+	// "decorrelated") randomises it
+	RetryTimeout Duration `json:"retry_timeout"`
+	RetryMax     Duration `json:"retry_max"`
+	RetryJitter  string   `json:"retry_jitter"`
+
+	// Topology names the overlay strategy, as accepted by topology.Parse
+	Topology string `json:"topology"`
+
+	// Mode selects "delta", "push-pull" or "plumtree" dissemination;
+	// Fanout, RumorLimit and PullInterval tune push-pull, GraftTimeout and
+	// IHaveInterval tune plumtree
+	Mode          string   `json:"mode"`
+	Fanout        int      `json:"fanout"`
+	RumorLimit    int      `json:"rumor_limit"`
+	PullInterval  Duration `json:"pull_interval"`
+	GraftTimeout  Duration `json:"graft_timeout"`
+	IHaveInterval Duration `json:"ihave_interval"`
+
+	// AntiEntropyInterval is how often a digest is exchanged; zero disables
+	// it. DigestWidth is how many consecutive values a digest bucket covers
+	AntiEntropyInterval Duration `json:"anti_entropy_interval"`
+	DigestWidth         int      `json:"digest_width"`
+
+	// ForwardTimeout bounds requests forwarded to another node
+	ForwardTimeout Duration `json:"forward_timeout"`
+
+	// IDScheme selects "counter" or "snowflake" IDs for generate
+	IDScheme string `json:"id_scheme"`
+
+	// Workload fixes read replies to "broadcast" or "g-counter"; empty
+	// infers it from the requests received
+	Workload string `json:"workload"`
+
+	// WALDir enables the write-ahead log under this directory when set;
+	// CompactEvery is how many records build up before it is compacted,
+	// zero never, and IDBlock how many counter IDs one record reserves
+	WALDir       string `json:"wal_dir"`
+	CompactEvery int    `json:"compact_every"`
+	IDBlock      int    `json:"id_block"`
+}
+
+// Default returns the parameters NewServer uses when nothing is configured.
+func Default() Config {
+	return Config{
+		GossipInterval:      Duration(50 * time.Millisecond),
+		GossipMax:           128,
+		RetryTimeout:        Duration(100 * time.Millisecond),
+		RetryMax:            Duration(time.Second),
+		RetryJitter:         "equal",
+		Topology:            "mesh",
+		Mode:                string(gossip.DeltaMode),
+		Fanout:              3,
+		RumorLimit:          2,
+		PullInterval:        Duration(time.Second),
+		GraftTimeout:        Duration(500 * time.Millisecond),
+		IHaveInterval:       Duration(500 * time.Millisecond),
+		AntiEntropyInterval: Duration(time.Second),
+		DigestWidth:         antientropy.DefaultBucketWidth,
+		ForwardTimeout:      Duration(time.Second),
+		IDScheme:            string(idgen.CounterScheme),
+		CompactEvery:        10000,
+		IDBlock:             1000,
+	}
+}
+
+// setting binds one Config field to its flag and environment variable.
+type setting struct {
+	flag, env, usage string
+	set              func(c *Config, v string) error
+}
+
+// settings lists every field that flags and the environment can override.
+var settings = []setting{
+	{"gossip-interval", "GOSSIP_INTERVAL", "time between gossip rounds", durationField(func(c *Config) *Duration { return &c.GossipInterval })},
+	{"gossip-max", "GOSSIP_MAX", "most values sent to one peer per round", intField(func(c *Config) *int { return &c.GossipMax })},
+	{"retry-timeout", "GOSSIP_RETRY_TIMEOUT", "first retransmission delay", durationField(func(c *Config) *Duration { return &c.RetryTimeout })},
+	{"retry-max", "GOSSIP_RETRY_MAX", "longest retransmission delay", durationField(func(c *Config) *Duration { return &c.RetryMax })},
+	{"retry-jitter", "GOSSIP_RETRY_JITTER", "backoff jitter: none, full, equal or decorrelated", stringField(func(c *Config) *string { return &c.RetryJitter })},
+	{"topology", "GOSSIP_TOPOLOGY", "overlay: given, mesh, ring, tree[:k], hub or grid", stringField(func(c *Config) *string { return &c.Topology })},
+	{"mode", "GOSSIP_MODE", "dissemination: delta, push-pull or plumtree", stringField(func(c *Config) *string { return &c.Mode })},
+	{"fanout", "GOSSIP_FANOUT", "random peers contacted per push-pull round", intField(func(c *Config) *int { return &c.Fanout })},
+	{"rumor-limit", "GOSSIP_RUMOR_LIMIT", "already-known replies before push-pull retires a value", intField(func(c *Config) *int { return &c.RumorLimit })},
+	{"pull-interval", "GOSSIP_PULL_INTERVAL", "time between push-pull pulls from a random peer, 0 to disable", durationField(func(c *Config) *Duration { return &c.PullInterval })},
+	{"graft-timeout", "GOSSIP_GRAFT_TIMEOUT", "wait for an announced value before plumtree grafts it", durationField(func(c *Config) *Duration { return &c.GraftTimeout })},
+	{"ihave-interval", "GOSSIP_IHAVE_INTERVAL", "time between plumtree ihave announcements", durationField(func(c *Config) *Duration { return &c.IHaveInterval })},
+	{"anti-entropy-interval", "GOSSIP_ANTI_ENTROPY_INTERVAL", "time between digest exchanges, 0 to disable", durationField(func(c *Config) *Duration { return &c.AntiEntropyInterval })},
+	{"digest-width", "GOSSIP_DIGEST_WIDTH", "consecutive values per anti-entropy digest bucket", intField(func(c *Config) *int { return &c.DigestWidth })},
+	{"forward-timeout", "GOSSIP_FORWARD_TIMEOUT", "timeout for requests forwarded to other nodes", durationField(func(c *Config) *Duration { return &c.ForwardTimeout })},
+	{"id-scheme", "GOSSIP_ID_SCHEME", "generate IDs: counter or snowflake", stringField(func(c *Config) *string { return &c.IDScheme })},
+	{"workload", "GOSSIP_WORKLOAD", "read replies for: broadcast or g-counter, empty to infer", stringField(func(c *Config) *string { return &c.Workload })},
+	{"wal-dir", "GOSSIP_WAL_DIR", "write-ahead log directory, empty to disable", stringField(func(c *Config) *string { return &c.WALDir })},
+	{"compact-every", "GOSSIP_COMPACT_EVERY", "write-ahead log records between compactions, 0 to never compact", intField(func(c *Config) *int { return &c.CompactEvery })},
+	{"id-block", "GOSSIP_ID_BLOCK", "counter IDs reserved per write-ahead log record", intField(func(c *Config) *int { return &c.IDBlock })},
+}
+
+func durationField(field func(*Config) *Duration) func(*Config, string) error {
+	return func(c *Config, v string) error {
+		return field(c).UnmarshalText([]byte(v))
+	}
+}
+
+func intField(field func(*Config) *int) func(*Config, string) error {
+	return func(c *Config, v string) error {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return err
+		}
+		*field(c) = n
+		return nil
+	}
+}
+
+func stringField(field func(*Config) *string) func(*Config, string) error {
+	return func(c *Config, v string) error {
+		*field(c) = v
+		return nil
+	}
+}
+
+// Load builds a Config from defaults, the config file, the environment and
+// args, in increasing precedence, and validates the result. The file is
+// named by the -config flag or EnvFile. Returns flag.ErrHelp for -h.
+func Load(args []string, getenv func(string) string) (Config, error) {
+	fs := flag.NewFlagSet("gossip", flag.ContinueOnError)
+	path := fs.String("config", getenv(EnvFile), "JSON config file")
+	flags := make(map[string]string)
+	for _, st := range settings {
+		fs.Func(st.flag, st.usage+" (env "+st.env+")", func(v string) error {
+			flags[st.flag] = v
+			return nil
+		})
+	}
+	if err := fs.Parse(args); err != nil {
+		return Config{}, err
+	}
+
+	cfg := Default()
+	if *path != "" {
+		if err := cfg.readFile(*path); err != nil {
+			return Config{}, err
+		}
+	}
+	for _, st := range settings {
+		if v := getenv(st.env); v != "" {
+			if err := st.set(&cfg, v); err != nil {
+				return Config{}, fmt.Errorf("config: bad %s %q: %w", st.env, v, err)
+			}
+		}
+	}
+	for _, st := range settings {
+		if v, ok := flags[st.flag]; ok {
+			if err := st.set(&cfg, v); err != nil {
+				return Config{}, fmt.Errorf("config: bad -%s %q: %w", st.flag, v, err)
+			}
+		}
+	}
+	return cfg, cfg.Validate()
+}
+
+// readFile overlays the fields present in the JSON file at path. Unknown
+// keys are rejected so a typo does not silently fall back to a default.
+func (c *Config) readFile(path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("config: %s: %w", path, err)
+	}
+	return nil
+}
+
+// Validate reports every out-of-range or unknown setting at once.
+func (c Config) Validate() error {
+	var errs []error
+	if c.GossipInterval <= 0 {
+		errs = append(errs, errors.New("gossip_interval must be positive"))
+	}
+	if c.GossipMax < 1 {
+		errs = append(errs, errors.New("gossip_max must be at least 1"))
+	}
+	if c.RetryTimeout <= 0 {
+		errs = append(errs, errors.New("retry_timeout must be positive"))
+	}
+	if c.RetryMax < c.RetryTimeout {
+		errs = append(errs, errors.New("retry_max must not be below retry_timeout"))
+	}
+	if _, err := gossip.ParseJitter(c.RetryJitter); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := topology.Parse(c.Topology); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := gossip.ParseMode(c.Mode); err != nil {
+		errs = append(errs, err)
+	}
+	if c.Fanout < 1 {
+		errs = append(errs, errors.New("fanout must be at least 1"))
+	}
+	if c.RumorLimit < 1 {
+		errs = append(errs, errors.New("rumor_limit must be at least 1"))
+	}
+	if c.PullInterval < 0 {
+		errs = append(errs, errors.New("pull_interval must not be negative"))
+	}
+	if c.GraftTimeout <= 0 {
+		errs = append(errs, errors.New("graft_timeout must be positive"))
+	}
+	if c.IHaveInterval <= 0 {
+		errs = append(errs, errors.New("ihave_interval must be positive"))
+	}
+	if c.AntiEntropyInterval < 0 {
+		errs = append(errs, errors.New("anti_entropy_interval must not be negative"))
+	}
+	if c.DigestWidth < 1 {
+		errs = append(errs, errors.New("digest_width must be at least 1"))
+	}
+	if c.ForwardTimeout <= 0 {
+		errs = append(errs, errors.New("forward_timeout must be positive"))
+	}
+	if _, err := idgen.ParseScheme(c.IDScheme); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := gossip.ParseWorkload(c.Workload); err != nil {
+		errs = append(errs, err)
+	}
+	if c.CompactEvery < 0 {
+		errs = append(errs, errors.New("compact_every must not be negative"))
+	}
+	if c.IDBlock < 1 {
+		errs = append(errs, errors.New("id_block must be at least 1"))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	return nil
+}
+
+// Options returns the NewServer options the config calls for.
+func (c Config) Options() []gossip.Option {
+	var opts []gossip.Option
+	if c.WALDir != "" {
+		opts = append(opts, gossip.WithWAL(c.WALDir))
+	}
+	return opts
+}
+
+// Apply sets s's tunable fields. Call it before the node starts running.
+func (c Config) Apply(s *gossip.Server) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	jitter, _ := gossip.ParseJitter(c.RetryJitter)
+	strategy, _ := topology.Parse(c.Topology)
+	scheme, _ := idgen.ParseScheme(c.IDScheme)
+	mode, _ := gossip.ParseMode(c.Mode)
+	workload, _ := gossip.ParseWorkload(c.Workload)
+
+	s.GossipInterval = time.Duration(c.GossipInterval)
+	s.GossipMax = c.GossipMax
+	s.RetryTimeout = time.Duration(c.RetryTimeout)
+	s.Retry = gossip.NewRetryPolicy(time.Duration(c.RetryTimeout), time.Duration(c.RetryMax), jitter)
+	s.Topology = strategy
+	s.Mode = mode
+	s.Fanout = c.Fanout
+	s.RumorLimit = c.RumorLimit
+	s.PullInterval = time.Duration(c.PullInterval)
+	s.GraftTimeout = time.Duration(c.GraftTimeout)
+	s.IHaveInterval = time.Duration(c.IHaveInterval)
+	s.AntiEntropyInterval = time.Duration(c.AntiEntropyInterval)
+	s.DigestWidth = c.DigestWidth
+	s.ForwardTimeout = time.Duration(c.ForwardTimeout)
+	s.IDScheme = scheme
+	s.Workload = workload
+	s.CompactEvery = c.CompactEvery
+	s.IDBlock = uint64(c.IDBlock)
+	return nil
+}
+
+// LogValue renders the config as one group for the startup log line.
+func (c Config) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Duration("gossip_interval", time.Duration(c.GossipInterval)),
+		slog.Int("gossip_max", c.GossipMax),
+		slog.Duration("retry_timeout", time.Duration(c.RetryTimeout)),
+		slog.Duration("retry_max", time.Duration(c.RetryMax)),
+		slog.String("retry_jitter", c.RetryJitter),
+		slog.String("topology", c.Topology),
+		slog.String("mode", c.Mode),
+		slog.Int("fanout", c.Fanout),
+		slog.Int("rumor_limit", c.RumorLimit),
+		slog.Duration("pull_interval", time.Duration(c.PullInterval)),
+		slog.Duration("graft_timeout", time.Duration(c.GraftTimeout)),
+		slog.Duration("ihave_interval", time.Duration(c.IHaveInterval)),
+		slog.Duration("anti_entropy_interval", time.Duration(c.AntiEntropyInterval)),
+		slog.Int("digest_width", c.DigestWidth),
+		slog.Duration("forward_timeout", time.Duration(c.ForwardTimeout)),
+		slog.String("id_scheme", c.IDScheme),
+		slog.String("workload", c.Workload),
+		slog.String("wal_dir", c.WALDir),
+		slog.Int("compact_every", c.CompactEvery),
+		slog.Int("id_block", c.IDBlock),
+	)
+}
@@ -0,0 +1,141 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"maelstrom-broadcast/internal/gossip"
+	"maelstrom-broadcast/internal/idgen"
+	"maelstrom-broadcast/internal/sim"
+	"maelstrom-broadcast/internal/topology"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func env(vars map[string]string) func(string) string {
+	return func(k string) string { return vars[k] }
+}
+
+func TestDefault_MatchesNewServer(t *testing.T) {
+	s := gossip.NewServer(sim.NewNetwork(sim.Config{}, []string{"n0"}).Node("n0"))
+	cfg := Default()
+	require.NoError(t, cfg.Validate())
+
+	assert.Equal(t, s.GossipInterval, time.Duration(cfg.GossipInterval))
+	assert.Equal(t, s.RetryTimeout, time.Duration(cfg.RetryTimeout))
+	assert.Equal(t, s.GossipMax, cfg.GossipMax)
+	assert.Equal(t, s.AntiEntropyInterval, time.Duration(cfg.AntiEntropyInterval))
+	assert.Equal(t, s.ForwardTimeout, time.Duration(cfg.ForwardTimeout))
+	assert.Equal(t, s.IDScheme, idgen.Scheme(cfg.IDScheme))
+	assert.Equal(t, s.Workload, gossip.Workload(cfg.Workload))
+	assert.Equal(t, s.Mode, gossip.Mode(cfg.Mode))
+	assert.Equal(t, s.Fanout, cfg.Fanout)
+	assert.Equal(t, s.RumorLimit, cfg.RumorLimit)
+	assert.Equal(t, s.PullInterval, time.Duration(cfg.PullInterval))
+	assert.Equal(t, s.GraftTimeout, time.Duration(cfg.GraftTimeout))
+	assert.Equal(t, s.IHaveInterval, time.Duration(cfg.IHaveInterval))
+	assert.Equal(t, s.DigestWidth, cfg.DigestWidth)
+	assert.Equal(t, s.CompactEvery, cfg.CompactEvery)
+	assert.Equal(t, s.IDBlock, uint64(cfg.IDBlock))
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gossip.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{
+		"gossip_interval": "20ms",
+		"gossip_max": 64,
+		"topology": "ring"
+	}`), 0o644))
+
+	cfg, err := Load([]string{"-gossip-max", "32"}, env(map[string]string{
+		EnvFile:           path,
+		"GOSSIP_TOPOLOGY": "tree:4",
+		"GOSSIP_MAX":      "16",
+	}))
+	require.NoError(t, err)
+
+	assert.Equal(t, Duration(20*time.Millisecond), cfg.GossipInterval, "file overrides default")
+	assert.Equal(t, "tree:4", cfg.Topology, "env overrides file")
+	assert.Equal(t, 32, cfg.GossipMax, "flag overrides env")
+	assert.Equal(t, Default().RetryTimeout, cfg.RetryTimeout, "unset keeps default")
+}
+
+func TestLoad_ConfigFlag(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gossip.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"retry_jitter": "none"}`), 0o644))
+
+	cfg, err := Load([]string{"-config", path}, env(nil))
+	require.NoError(t, err)
+	assert.Equal(t, "none", cfg.RetryJitter)
+}
+
+func TestLoad_Errors(t *testing.T) {
+	dir := t.TempDir()
+	unknown := filepath.Join(dir, "unknown.json")
+	require.NoError(t, os.WriteFile(unknown, []byte(`{"gossip_intervall": "20ms"}`), 0o644))
+
+	tests := map[string]struct {
+		args []string
+		env  map[string]string
+	}{
+		"unknown file key":   {args: []string{"-config", unknown}},
+		"missing file":       {args: []string{"-config", filepath.Join(dir, "nope.json")}},
+		"bad env duration":   {env: map[string]string{"GOSSIP_INTERVAL": "fast"}},
+		"bad flag int":       {args: []string{"-gossip-max", "lots"}},
+		"zero gossip max":    {args: []string{"-gossip-max", "0"}},
+		"unknown topology":   {args: []string{"-topology", "star"}},
+		"unknown jitter":     {env: map[string]string{"GOSSIP_RETRY_JITTER": "some"}},
+		"retry max too low":  {args: []string{"-retry-timeout", "2s", "-retry-max", "1s"}},
+		"unknown scheme":     {args: []string{"-id-scheme", "uuid"}},
+		"unknown workload":   {env: map[string]string{"GOSSIP_WORKLOAD": "kafka"}},
+		"unknown mode":       {env: map[string]string{"GOSSIP_MODE": "flood"}},
+		"zero fanout":        {args: []string{"-mode", "push-pull", "-fanout", "0"}},
+		"zero rumor limit":   {args: []string{"-rumor-limit", "0"}},
+		"negative pull":      {args: []string{"-pull-interval", "-1s"}},
+		"zero graft timeout": {args: []string{"-mode", "plumtree", "-graft-timeout", "0s"}},
+		"zero digest width":  {args: []string{"-digest-width", "0"}},
+		"negative compact":   {env: map[string]string{"GOSSIP_COMPACT_EVERY": "-1"}},
+		"zero id block":      {args: []string{"-id-block", "0"}},
+		"unknown flag":       {args: []string{"-gossip-speed", "fast"}},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := Load(tt.args, env(tt.env))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	cfg, err := Load([]string{
+		"-gossip-interval", "25ms",
+		"-gossip-max", "10",
+		"-retry-timeout", "40ms",
+		"-topology", "tree:3",
+		"-id-scheme", "snowflake",
+		"-mode", "push-pull",
+		"-fanout", "5",
+		"-digest-width", "16",
+		"-compact-every", "0",
+		"-id-block", "50",
+	}, env(nil))
+	require.NoError(t, err)
+
+	s := gossip.NewServer(sim.NewNetwork(sim.Config{}, []string{"n0"}).Node("n0"), cfg.Options()...)
+	require.NoError(t, cfg.Apply(s))
+
+	assert.Equal(t, 25*time.Millisecond, s.GossipInterval)
+	assert.Equal(t, 10, s.GossipMax)
+	assert.Equal(t, 40*time.Millisecond, s.RetryTimeout)
+	assert.Equal(t, topology.Tree{K: 3}, s.Topology)
+	assert.Equal(t, idgen.SnowflakeScheme, s.IDScheme)
+	assert.Equal(t, gossip.PushPullMode, s.Mode)
+	assert.Equal(t, 5, s.Fanout)
+	assert.Equal(t, 16, s.DigestWidth)
+	assert.Equal(t, 0, s.CompactEvery)
+	assert.Equal(t, uint64(50), s.IDBlock)
+	assert.Equal(t, gossip.NewRetryPolicy(40*time.Millisecond, time.Second, gossip.EqualJitter), s.Retry)
+}
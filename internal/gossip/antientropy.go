@@ -0,0 +1,149 @@
+package gossip
+
+import (
+	// --- Standard Lib ---
+	"time"
+
+	// --- Internal Lib ---
+	"maelstrom-broadcast/internal/antientropy"
+	"maelstrom-broadcast/internal/protocol"
+
+	// --- Third Party ---
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+)
+
+// HandleDigest compares a peer's digest with the local message set.
+// A request naming buckets in Want is answered with the local values in
+// those buckets, and one naming nodes in Expand with the local summaries of
+// their children. Otherwise the reply is either in_sync or the top level of
+// the local digest tree, from which the requester starts descending. A
+// version vector sent along is handed to the requester's actor.
+func (s *Server) HandleDigest(msg maelstrom.Message) error {
+	return handle(msg, func(req protocol.DigestReq) error {
+		s.observe(msg.Src)
+		if a, ok := s.peer(msg.Src); ok && req.Have != nil {
+			a.cast(haveMsg{have: req.Have})
+		}
+		values := s.Messages.Snapshot()
+
+		if len(req.Want) > 0 {
+			return s.Node.Reply(msg, protocol.DigestOK{
+				Type:   "digest_ok",
+				Values: antientropy.Select(values, req.Width, req.Want),
+			})
+		}
+
+		d := antientropy.Build(values, req.Width)
+		if len(req.Expand) > 0 {
+			return s.Node.Reply(msg, protocol.DigestOK{
+				Type:   "digest_ok",
+				Width:  req.Width,
+				Level:  req.Level,
+				Within: req.Expand,
+				Ranges: toRangeDigests(d.Level(req.Level, req.Expand)),
+			})
+		}
+		if d.Matches(req.Root, req.Count) {
+			return s.Node.Reply(msg, protocol.DigestOK{Type: "digest_ok", InSync: true})
+		}
+		top := antientropy.Levels - 1
+		return s.Node.Reply(msg, protocol.DigestOK{
+			Type:   "digest_ok",
+			Width:  req.Width,
+			Level:  top,
+			Ranges: toRangeDigests(d.Level(top, nil)),
+		})
+	})
+}
+
+// HandleDigestOK acts on a digest reply. Tree nodes are diffed against the
+// local tree built with the width the reply names. Above the bucket level
+// the peer is asked to expand the nodes that differ; at the bucket level
+// local values in differing buckets are resent to the peer, and the peer is
+// asked for its values in those buckets. Values received in answer are
+// added like any other delta and forwarded to the other neighbours.
+func (s *Server) HandleDigestOK(msg maelstrom.Message) error {
+	return handle(msg, func(resp protocol.DigestOK) error {
+		s.observe(msg.Src)
+
+		for _, v := range resp.Values {
+			if _, err := s.addMessage(v, msg.Src); err != nil {
+				return err
+			}
+		}
+		if resp.InSync || resp.Width < 1 {
+			return nil
+		}
+
+		values := s.Messages.Snapshot()
+		d := antientropy.Build(values, resp.Width)
+		differ := d.DiffLevel(resp.Level, fromRangeDigests(resp.Ranges), resp.Within)
+		if len(differ) == 0 {
+			return nil
+		}
+
+		req := protocol.DigestReq{
+			Type:  "digest",
+			Root:  d.Root(),
+			Count: d.Count(),
+			Width: resp.Width,
+		}
+		if resp.Level > 0 {
+			req.Level = resp.Level - 1
+			req.Expand = differ
+			return s.Node.Send(msg.Src, req)
+		}
+		s.logs().gossip.Debug("digest mismatch", "peer", msg.Src, "buckets", len(differ))
+
+		s.resend(msg.Src, antientropy.Select(values, resp.Width, differ))
+		req.Want = differ
+		return s.Node.Send(msg.Src, req)
+	})
+}
+
+// sendDigest starts an anti-entropy exchange with one reachable neighbour
+// every AntiEntropyInterval, cycling through neighbours in ID order. This
+// repairs anything the delta queues missed, such as values lost when a
+// neighbour's queue was dropped by a topology change, and carries this
+// node's version vector to neighbours no deltas are sent to.
+func (s *Server) sendDigest(now time.Time, ids []string) error {
+	s.syncMU.Lock()
+	if s.AntiEntropyInterval <= 0 || now.Before(s.syncAt) || len(ids) == 0 {
+		s.syncMU.Unlock()
+		return nil
+	}
+	s.syncAt = now.Add(s.AntiEntropyInterval)
+	s.syncNext++
+	peerID := ids[s.syncNext%len(ids)]
+	s.syncMU.Unlock()
+
+	if !s.reachable(peerID) {
+		return nil
+	}
+	d := antientropy.Build(s.Messages.Snapshot(), s.DigestWidth)
+	return s.Node.Send(peerID, protocol.DigestReq{
+		Type:  "digest",
+		Root:  d.Root(),
+		Count: d.Count(),
+		Width: s.DigestWidth,
+		Have:  s.Versions.Vector(),
+	})
+}
+
+// toRangeDigests converts bucket summaries to their wire form.
+func toRangeDigests(in []antientropy.Summary) []protocol.RangeDigest {
+	out := make([]protocol.RangeDigest, len(in))
+	for i, b := range in {
+		out[i] = protocol.RangeDigest{Lo: b.Lo, Count: b.Count, Hash: b.Hash}
+	}
+	return out
+}
+
+// fromRangeDigests converts wire bucket summaries back.
+func fromRangeDigests(in []protocol.RangeDigest) []antientropy.Summary {
+	out := make([]antientropy.Summary, len(in))
+	for i, b := range in {
+		out[i] = antientropy.Summary{Lo: b.Lo, Count: b.Count, Hash: b.Hash}
+	}
+	return out
+}
@@ -0,0 +1,46 @@
+package gossip
+
+import (
+	// --- Standard Lib ---
+	"context"
+	"errors"
+
+	// --- Internal Lib ---
+	"maelstrom-broadcast/internal/kv"
+	"maelstrom-broadcast/internal/protocol"
+
+	// --- Third Party ---
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+)
+
+// errorReply classifies a handler error as a Maelstrom error reply.
+// Typed replies pass through, errors from other nodes and KV services keep
+// their codes, deadlines become timeouts, and anything else is a crash.
+func errorReply(err error) *protocol.ErrorReply {
+	var reply *protocol.ErrorReply
+	var rpc *maelstrom.RPCError
+	switch {
+	case errors.As(err, &reply):
+		return reply
+	case errors.Is(err, kv.ErrKeyDoesNotExist):
+		return protocol.NewError(protocol.KeyDoesNotExist, err.Error())
+	case errors.Is(err, kv.ErrPreconditionFailed):
+		return protocol.NewError(protocol.PreconditionFailed, err.Error())
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
+		return protocol.NewError(protocol.Timeout, err.Error())
+	case errors.As(err, &rpc):
+		return protocol.NewError(protocol.ErrorCode(rpc.Code), rpc.Text)
+	default:
+		return protocol.NewError(protocol.Crash, err.Error())
+	}
+}
+
+// asRPCError converts a handler error into the form Maelstrom transports
+// send back as an error reply. Returns nil for nil.
+func asRPCError(err error) error {
+	if err == nil {
+		return nil
+	}
+	reply := errorReply(err)
+	return maelstrom.NewRPCError(int(reply.Code), reply.Text)
+}
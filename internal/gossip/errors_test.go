@@ -0,0 +1,59 @@
+package gossip
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"maelstrom-broadcast/internal/kv"
+	"maelstrom-broadcast/internal/protocol"
+	"maelstrom-broadcast/internal/sim"
+
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorReply_Classifies(t *testing.T) {
+	cases := []struct {
+		err  error
+		want protocol.ErrorCode
+	}{
+		{protocol.NewError(protocol.Abort, "x"), protocol.Abort},
+		{fmt.Errorf("ctx: %w", protocol.NewError(protocol.TxnConflict, "x")), protocol.TxnConflict},
+		{fmt.Errorf("%w: lin-kv/k", kv.ErrKeyDoesNotExist), protocol.KeyDoesNotExist},
+		{fmt.Errorf("%w: lin-kv/k", kv.ErrPreconditionFailed), protocol.PreconditionFailed},
+		{context.DeadlineExceeded, protocol.Timeout},
+		{maelstrom.NewRPCError(maelstrom.TemporarilyUnavailable, "busy"), protocol.TemporarilyUnavailable},
+		{errors.New("boom"), protocol.Crash},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.want, errorReply(c.err).Code, c.err.Error())
+	}
+	assert.Nil(t, asRPCError(nil))
+}
+
+func TestSim_ErrorReplies(t *testing.T) {
+	net := sim.NewNetwork(sim.Config{Seed: 23}, []string{"n0"})
+	node := net.Node("n0")
+	s := NewServer(node)
+	s.Clock = net
+	s.Register(node)
+	net.Init()
+
+	net.Inject("c1", "n0", map[string]any{"type": "broadcast", "message": "not a number", "msg_id": 1})
+	net.Inject("c1", "n0", map[string]any{"type": "txn", "txn": [][]any{{"append", 1, 2}}, "msg_id": 2})
+	net.RunFor(time.Second)
+
+	var codes []protocol.ErrorCode
+	for _, r := range net.Replies("c1") {
+		var reply protocol.ErrorReply
+		require.NoError(t, json.Unmarshal(r.Body, &reply))
+		require.Equal(t, "error", reply.Type)
+		codes = append(codes, reply.Code)
+	}
+	assert.ElementsMatch(t, []protocol.ErrorCode{protocol.MalformedRequest, protocol.NotSupported}, codes)
+}
@@ -1,17 +1,16 @@
 // Package gossip provides message handlers for the distributed gossip protocol.
 // This file implements handlers for all Maelstrom message types including echo,
-// unique ID generation, broadcast, read, topology setup, and delta synchronization.
+// unique ID generation, broadcast, g-counter, read, topology setup, and delta synchronization.
 package gossip
 
 import (
 	// --- Standard Lib ---
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
 
 	// --- Internal Lib ---
 	"maelstrom-broadcast/internal/protocol"
-	"maelstrom-broadcast/internal/queue"
 
 	// --- Third Party ---
 	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
@@ -25,32 +24,39 @@ import (
 //   - msg: The incoming Maelstrom message
 //   - fn: Handler function that processes the unmarshaled message
 //
-// Returns an error if unmarshaling fails or if the handler function returns an error.
+// The decoded request is checked against its validate tags before fn runs.
+// Errors are translated into Maelstrom error replies: a body that does not
+// decode or validate is a malformed-request, a *protocol.ErrorReply keeps its
+// code, and other errors are classified by errorReply.
 func handle[T any](msg maelstrom.Message, fn func(T) error) error {
-	log.Printf("DEBUG: handle called with message type %T", *new(T))
 	var req T
 	if err := json.Unmarshal(msg.Body, &req); err != nil {
-		log.Printf("DEBUG: JSON unmarshal failed: %v", err)
-		return err
+		return asRPCError(protocol.Errorf(protocol.MalformedRequest, "decode %T: %v", req, err))
+	}
+	if err := protocol.Validate(msg.Body, &req); err != nil {
+		return asRPCError(err)
 	}
-	log.Printf("DEBUG: JSON unmarshal succeeded, calling handler")
-	return fn(req)
+	return asRPCError(fn(req))
 }
 
 // HandleEcho processes echo requests from Maelstrom for connectivity testing.
 // This is a simple ping-pong handler that immediately responds with an echo_ok message.
 func (s *Server) HandleEcho(msg maelstrom.Message) error {
 	return handle(msg, func(req protocol.EchoReq) error {
-		resp := protocol.EchoOK{Type: "echo_ok"}
+		resp := protocol.EchoOK{Type: "echo_ok", Echo: req.Echo}
 		return s.Node.Reply(msg, resp)
 	})
 }
 
 // HandleGenerate creates globally unique IDs for the distributed system.
-// Uses atomic counter combined with node ID to ensure uniqueness across all nodes.
+// IDs come from the generator chosen by IDScheme, which needs no
+// coordination between nodes to stay unique.
 func (s *Server) HandleGenerate(msg maelstrom.Message) error {
 	return handle(msg, func(req protocol.GenerateReq) error {
-		uid := fmt.Sprintf("%s_%d", s.Node.ID(), s.Counter.Add(1))
+		uid, err := s.IDs.Next()
+		if err != nil {
+			return protocol.Errorf(protocol.TemporarilyUnavailable, "generate: %v", err)
+		}
 		resp := protocol.GenerateOK{
 			Type: "generate_ok",
 			ID:   uid,
@@ -61,24 +67,13 @@ func (s *Server) HandleGenerate(msg maelstrom.Message) error {
 
 // HandleBroadcast receives new messages to be distributed across the network.
 // If the message is new (not already seen), it's added to the global message set
-// and queued for gossip propagation to all peer nodes.
+// and tagged as this node's next entry for gossip propagation to all peer nodes.
 func (s *Server) HandleBroadcast(msg maelstrom.Message) error {
-	log.Printf("DEBUG: HandleBroadcast received message %v", msg)
 	return handle(msg, func(req protocol.BroadcastReq) error {
-		log.Printf("DEBUG: Processing broadcast request %+v", req)
-		if s.Messages.Add(req.Message) {
-			log.Printf("DEBUG: Added new message %d", req.Message)
-			if s.Pending != nil {
-				for peer, pq := range s.Pending {
-					if peer != s.Node.ID() {
-						pq.Add(req.Message)
-					}
-				}
-			}
-		} else {
-			log.Printf("DEBUG: Message %d already exists", req.Message)
+		s.served(BroadcastWorkload)
+		if _, err := s.addBroadcast(req.Message, msg.Src); err != nil {
+			return err
 		}
-		log.Printf("DEBUG: About to send broadcast_ok")
 		resp := protocol.BroadcastOK{
 			Type: "broadcast_ok",
 		}
@@ -86,13 +81,35 @@ func (s *Server) HandleBroadcast(msg maelstrom.Message) error {
 	})
 }
 
+// HandleAdd increments this node's contribution to the grow-only counter.
+// The new total is propagated to peers by the background gossip loop.
+func (s *Server) HandleAdd(msg maelstrom.Message) error {
+	return handle(msg, func(req protocol.AddReq) error {
+		s.served(CounterWorkload)
+		s.Counters.Add(s.Node.ID(), req.Delta)
+		resp := protocol.AddOK{
+			Type: "add_ok",
+		}
+		return s.Node.Reply(msg, resp)
+	})
+}
+
 // HandleRead returns all messages currently known to this node.
 // Provides a consistent snapshot of the distributed message set.
+// The broadcast and g-counter workloads share the "read" message type, so
+// nodes serving the g-counter workload reply with the counter value instead;
+// see Workload.
 func (s *Server) HandleRead(msg maelstrom.Message) error {
 	return handle(msg, func(req protocol.ReadReq) error {
+		if s.workload() == CounterWorkload {
+			return s.Node.Reply(msg, protocol.CounterReadOK{
+				Type:  "read_ok",
+				Value: s.Counters.Value(),
+			})
+		}
 		resp := protocol.ReadOK{
 			Type:     "read_ok",
-			Messages: s.Messages.GetSlice(),
+			Messages: s.Messages.Snapshot(),
 		}
 		return s.Node.Reply(msg, resp)
 	})
@@ -120,27 +137,47 @@ func (s *Server) HandleRead(msg maelstrom.Message) error {
 
 
 
+// HandleInit runs after the Maelstrom node has processed its init message.
+// Workloads such as g-counter never send a topology message, so peer queues
+// and the gossip loop are started here as soon as the node IDs are known.
+// The ID generator is built here too, since Snowflake IDs need the node's
+// index in the cluster, after any write-ahead log has been replayed, and
+// replayed values are tagged as this node's entries.
+func (s *Server) HandleInit(msg maelstrom.Message) error {
+	if s.walDir != "" {
+		if err := s.restore(); err != nil {
+			return err
+		}
+	}
+	ids, err := s.newIDs()
+	if err != nil {
+		return err
+	}
+	s.IDs = ids
+	s.Txns.SetNode(s.Node.ID())
+	s.Versions.SetOrigin(fmt.Sprintf("%s.%d", s.Node.ID(), s.Clock.Now().UnixNano()))
+	if s.WAL != nil && s.Mode != PushPullMode {
+		// The WAL keeps values, not entries: tagging the recovered values
+		// afresh lets neighbours that missed them before the restart catch up
+		for _, v := range s.WAL.Recovered().Messages {
+			s.Versions.Append(v)
+		}
+	}
+	s.logs().gossip.Info("initialized", "nodes", len(s.Node.NodeIDs()), "id_scheme", s.IDScheme,
+		"wal", s.WAL != nil, "messages", s.Messages.Len())
+	s.initPeers()
+	return nil
+}
+
 // HandleTopology initializes the gossip network topology.
-// Sets up peer queues for all other nodes and starts the background gossip loop.
-// Uses sync.Once to ensure initialization happens only once per server instance.
+// Recomputes this node's neighbours with the configured Topology strategy,
+// which may use or ignore Maelstrom's suggestion, and starts the background
+// gossip loop if init has not already done so.
 func (s *Server) HandleTopology(msg maelstrom.Message) error {
 	return handle(msg, func(req protocol.TopologyReq) error {
-		s.initOnce.Do(func() {
-			s.Pending = make(map[string]*queue.Peer)
-
-			s.Messages.MU.RLock()
-			baseValues := s.Messages.Values
-			s.Messages.MU.RUnlock()
-
-			for _, peer := range s.Node.NodeIDs() {
-				if peer == s.Node.ID() {
-					continue
-				}
-
-				s.Pending[peer] = queue.NewPeerQueueFromMap(baseValues)
-			}
-			go s.HandlePeerQueues()
-		})
+		s.served(BroadcastWorkload)
+		s.initPeers()
+		s.setNeighbors(req.Topology)
 		resp := protocol.TopologyOK{
 			Type: "topology_ok",
 		}
@@ -150,59 +187,104 @@ func (s *Server) HandleTopology(msg maelstrom.Message) error {
 
 // HandleDelta processes batch message updates from peer nodes in the gossip protocol.
 // For each new message received, adds it to the local message set and propagates
-// it to all other peers. This enables efficient gossip-based message dissemination.
+// it to every overlay neighbour except the sender, so forwarding follows the topology.
+// The sender's version vector is handed to its actor, so nothing it already
+// holds is sent back, and the reply carries this node's vector after the
+// delta is applied. In Plumtree mode a first delivery with nothing new
+// prunes the link it came over. A value that cannot be persisted fails the delta
+// without an ack, so the sender retries the batch.
 func (s *Server) HandleDelta(msg maelstrom.Message) error {
 	return handle(msg, func(req protocol.DeltaReq) error {
+		s.observe(msg.Src)
+		if a, ok := s.peer(msg.Src); ok {
+			a.cast(haveMsg{have: req.Have})
+		}
+		if len(req.Counters) > 0 {
+			s.Counters.Merge(req.Counters)
+		}
+		if len(req.Txns) > 0 {
+			s.Txns.Merge(req.Txns)
+		}
+		if len(req.Skip) > 0 {
+			s.Versions.Skip(req.Skip)
+		}
+		// In Plumtree mode only duplicates src delivers for the first time
+		// count towards pruning; a retransmission after a lost delta_ok
+		// always repeats what this node already holds
+		tree := s.Mode == PlumtreeMode
+		fresh, dup := 0, 0
+		for _, e := range req.Entries {
+			first := tree && s.tree.FirstEntry(msg.Src, e.Origin, e.Seq)
+			added, err := s.addEntry(e, msg.Src)
+			if err != nil {
+				return err
+			}
+			switch {
+			case added:
+				fresh++
+			case first:
+				dup++
+			}
+		}
+		firstBatch := tree && s.tree.FirstBatch(msg.Src, req.BatchID)
 		for _, v := range req.Messages {
-			if s.Messages.Add(v) {
-				for peer, pq := range s.Pending {
-					if peer != s.Node.ID() {
-						if peer != msg.Src {
-							pq.Add(v)
-						}
-					}
-				}
+			added, err := s.addMessage(v, msg.Src)
+			if err != nil {
+				return err
+			}
+			switch {
+			case added:
+				fresh++
+			case firstBatch:
+				dup++
+			}
+		}
+		if tree {
+			// The delta was applied, so a failed prune must not turn the
+			// ack into an error reply as well
+			if err := s.treeDelivered(msg.Src, fresh, dup); err != nil {
+				s.logs().gossip.Warn("prune failed", "peer", msg.Src, "err", err)
 			}
 		}
 		resp := protocol.DeltaOK{
-			Type: "delta_ok",
+			Type:           "delta_ok",
+			BatchID:        req.BatchID,
+			Have:           s.Versions.Vector(),
+			CounterVersion: req.CounterVersion,
+			TxnUpto:        req.TxnUpto,
 		}
 		return s.Node.Reply(msg, resp)
 	})
 }
 
 // HandleDeltaOK processes acknowledgments from peers for successfully delivered delta messages.
-// Stops the retry timer for the acknowledging peer to prevent unnecessary retransmissions.
-// This implements the reliability mechanism for the gossip protocol.
-// TODO: Much of this logic probably needs to live in PeerQueue and this needs proper typing with the DeltaOK struct.
+// The peer's actor records the peer's version vector and retires the
+// in-flight batch only if the ack names it, so a late ack for an older batch
+// cannot drop a newer one, then sends whatever the peer still lacks straight
+// away. The retry backoff is only reset by an ack that made progress, so a
+// stale or duplicate ack cannot bring a retransmission forward.
 func (s *Server) HandleDeltaOK(msg maelstrom.Message) error {
 	return handle(msg, func(req protocol.DeltaOK) error {
 		peerID := msg.Src // Maelstrom sets the sender ID here
-		if pq, ok := s.Pending[peerID]; ok {
-			pq.MU.Lock()
-			pq.InFlight = nil // Clear only the in-flight messages
-			pq.MU.Unlock()
-
-			next := pq.DrainBatch(s.GossipMax)
-
-			if len(next) == 0 {
-				for _, m := range s.Messages.GetSlice() {
-					pq.Add(m)
-				}
-				next = pq.DrainBatch(s.GossipMax)
-			}
-
-			if len(next) > 0 {
-				pq.MU.Lock()
-				pq.InFlight = next
-				pq.MU.Unlock()
-
-				s.Node.Send(peerID, protocol.DeltaReq{
-					Type:     "delta",
-					Messages: next,
-				})
+		s.observe(peerID)
+		if a, ok := s.peer(peerID); ok {
+			// An ack is never answered, so a failed follow-up delta is only
+			// logged; its retry timer resends it
+			err := a.call(ackMsg{resp: req, now: s.Clock.Now()})
+			if err != nil && !errors.Is(err, errPeerStopped) {
+				s.logs().gossip.Warn("delta after ack failed", "peer", peerID, "err", err)
 			}
 		}
 		return nil
 	})
 }
+
+// HandleError logs an error reply from a peer. Peer requests are sent
+// without a msg_id, so their error replies arrive as ordinary messages
+// rather than RPC results; the sender's retry loop recovers on its own.
+func (s *Server) HandleError(msg maelstrom.Message) error {
+	return handle(msg, func(resp protocol.ErrorReply) error {
+		s.logs().handler.Warn("peer error", "src", msg.Src, "code", resp.Code, "text", resp.Text)
+		return nil
+	})
+}
@@ -0,0 +1,90 @@
+package gossip
+
+import (
+	// --- Standard Lib ---
+	"errors"
+	"time"
+
+	// --- Internal Lib ---
+	"maelstrom-broadcast/internal/health"
+	"maelstrom-broadcast/internal/protocol"
+
+	// --- Third Party ---
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+)
+
+// HandleProbe answers a liveness probe. Direct probes are answered to the
+// sender; relayed probes carry the original requester in Origin so the
+// relaying node can pass the answer on.
+func (s *Server) HandleProbe(msg maelstrom.Message) error {
+	return handle(msg, func(req protocol.ProbeReq) error {
+		s.observe(msg.Src)
+		return s.Node.Reply(msg, protocol.ProbeOK{
+			Type:   "probe_ok",
+			Target: s.Node.ID(),
+			Origin: req.Origin,
+		})
+	})
+}
+
+// HandleProbeOK records that a probed node is alive. If the probe was sent on
+// behalf of another node, the answer is forwarded to that node.
+func (s *Server) HandleProbeOK(msg maelstrom.Message) error {
+	return handle(msg, func(req protocol.ProbeOK) error {
+		s.observe(msg.Src)
+		s.observe(req.Target)
+
+		if req.Origin != "" && req.Origin != s.Node.ID() {
+			return s.Node.Send(req.Origin, protocol.ProbeOK{
+				Type:   "probe_ok",
+				Target: req.Target,
+			})
+		}
+		return nil
+	})
+}
+
+// HandleIndirectProbe probes the requested target on the sender's behalf.
+func (s *Server) HandleIndirectProbe(msg maelstrom.Message) error {
+	return handle(msg, func(req protocol.IndirectProbeReq) error {
+		s.observe(msg.Src)
+		return s.Node.Send(req.Target, protocol.ProbeReq{
+			Type:   "probe",
+			Origin: msg.Src,
+		})
+	})
+}
+
+// observe records contact with a peer. A peer coming back from suspect or
+// dead has its retry backoff cleared so gossip to it resumes immediately.
+func (s *Server) observe(id string) {
+	if !s.Health.Observe(id, s.Clock.Now()) {
+		return
+	}
+	s.logs().health.Info("peer recovered", "peer", id)
+	if a, ok := s.peer(id); ok {
+		a.cast(resetMsg{})
+	}
+}
+
+// sendProbes runs one failure detector round and sends the probes it asks for.
+func (s *Server) sendProbes(now time.Time) error {
+	var errs []error
+	for _, p := range s.Health.Tick(now) {
+		if p.Via == "" {
+			errs = append(errs, s.Node.Send(p.Target, protocol.ProbeReq{Type: "probe"}))
+			continue
+		}
+		errs = append(errs, s.Node.Send(p.Via, protocol.IndirectProbeReq{
+			Type:   "probe_req",
+			Target: p.Target,
+		}))
+	}
+	return errors.Join(errs...)
+}
+
+// reachable reports whether gossip to a peer is worth sending. Dead peers
+// are skipped until a probe shows they are back.
+func (s *Server) reachable(id string) bool {
+	return s.Health.State(id) != health.Dead
+}
@@ -0,0 +1,156 @@
+package gossip
+
+import (
+	// --- Standard Lib ---
+	"context"
+	"encoding/json"
+	"errors"
+
+	// --- Internal Lib ---
+	"maelstrom-broadcast/internal/kv"
+	"maelstrom-broadcast/internal/logstore"
+	"maelstrom-broadcast/internal/protocol"
+
+	// --- Third Party ---
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+)
+
+// commitKeyPrefix namespaces committed consumer offsets in lin-kv.
+const commitKeyPrefix = "commit/"
+
+// HandleSend appends a message to a keyed log.
+// Each key is owned by exactly one node, which assigns offsets locally; other
+// nodes forward the request to the owner so offsets stay gap-free cluster-wide.
+func (s *Server) HandleSend(msg maelstrom.Message) error {
+	return handle(msg, func(req protocol.SendReq) error {
+		owner := logstore.Owner(req.Key, s.Node.NodeIDs())
+		if owner == s.Node.ID() {
+			return s.Node.Reply(msg, protocol.SendOK{
+				Type:   "send_ok",
+				Offset: s.Logs.Append(req.Key, req.Msg),
+			})
+		}
+
+		var resp protocol.SendOK
+		if err := s.forward(owner, req, &resp); err != nil {
+			return err
+		}
+		return s.Node.Reply(msg, resp)
+	})
+}
+
+// HandlePoll returns messages from the requested logs starting at the given
+// offsets. Keys are grouped by owner; local keys are served directly and the
+// rest are fetched from their owners with a single poll each.
+func (s *Server) HandlePoll(msg maelstrom.Message) error {
+	return handle(msg, func(req protocol.PollReq) error {
+		byOwner := make(map[string]map[string]int)
+		for key, off := range req.Offsets {
+			owner := logstore.Owner(key, s.Node.NodeIDs())
+			if byOwner[owner] == nil {
+				byOwner[owner] = make(map[string]int)
+			}
+			byOwner[owner][key] = off
+		}
+
+		out := make(map[string][][2]int, len(req.Offsets))
+		for owner, offsets := range byOwner {
+			if owner == s.Node.ID() {
+				for key, off := range offsets {
+					out[key] = s.Logs.Poll(key, off, logstore.DefaultPollLimit)
+				}
+				continue
+			}
+
+			var resp protocol.PollOK
+			err := s.forward(owner, protocol.PollReq{Type: "poll", Offsets: offsets}, &resp)
+			if err != nil {
+				return err
+			}
+			for key, msgs := range resp.Msgs {
+				out[key] = msgs
+			}
+		}
+
+		return s.Node.Reply(msg, protocol.PollOK{
+			Type: "poll_ok",
+			Msgs: out,
+		})
+	})
+}
+
+// HandleCommitOffsets records consumer progress in lin-kv.
+// Commits only ever move forward, so a slow client cannot rewind an offset
+// another consumer already committed past.
+func (s *Server) HandleCommitOffsets(msg maelstrom.Message) error {
+	return handle(msg, func(req protocol.CommitOffsetsReq) error {
+		ctx := context.Background()
+		for key, off := range req.Offsets {
+			if err := s.commitOffset(ctx, key, off); err != nil {
+				return err
+			}
+		}
+		return s.Node.Reply(msg, protocol.CommitOffsetsOK{
+			Type: "commit_offsets_ok",
+		})
+	})
+}
+
+// HandleListCommittedOffsets returns the committed offset for each requested
+// key. Keys that were never committed are left out of the reply.
+func (s *Server) HandleListCommittedOffsets(msg maelstrom.Message) error {
+	return handle(msg, func(req protocol.ListCommittedOffsetsReq) error {
+		ctx := context.Background()
+		offsets := make(map[string]int, len(req.Keys))
+		for _, key := range req.Keys {
+			off, err := s.LinKV.ReadInt(ctx, commitKeyPrefix+key)
+			if errors.Is(err, kv.ErrKeyDoesNotExist) {
+				continue
+			} else if err != nil {
+				return err
+			}
+			offsets[key] = off
+		}
+		return s.Node.Reply(msg, protocol.ListCommittedOffsetsOK{
+			Type:    "list_committed_offsets_ok",
+			Offsets: offsets,
+		})
+	})
+}
+
+// commitOffset raises the committed offset for key to at least off using a
+// compare-and-swap loop against lin-kv.
+func (s *Server) commitOffset(ctx context.Context, key string, off int) error {
+	for {
+		cur, err := s.LinKV.ReadInt(ctx, commitKeyPrefix+key)
+		exists := err == nil
+		if err != nil && !errors.Is(err, kv.ErrKeyDoesNotExist) {
+			return err
+		}
+		if exists && cur >= off {
+			return nil
+		}
+
+		err = s.LinKV.CompareAndSwap(ctx, commitKeyPrefix+key, cur, off, !exists)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, kv.ErrPreconditionFailed) {
+			return err
+		}
+	}
+}
+
+// forward sends req to the owning node and decodes its reply into resp.
+// Bounded by ForwardTimeout so a partitioned owner fails the request instead
+// of hanging the handler.
+func (s *Server) forward(owner string, req any, resp any) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.ForwardTimeout)
+	defer cancel()
+
+	reply, err := s.Node.SyncRPC(ctx, owner, req)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(reply.Body, resp)
+}
@@ -0,0 +1,126 @@
+package gossip
+
+import (
+	// --- Standard Lib ---
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"time"
+)
+
+var (
+	// ErrAlreadyStarted is returned by Start on a server that has been started
+	ErrAlreadyStarted = errors.New("gossip: server already started")
+
+	// ErrStopped is returned by Start on a server that has been stopped
+	ErrStopped = errors.New("gossip: server stopped")
+)
+
+// Start launches the background gossip loop, which runs a round every
+// GossipInterval until ctx is done, Stop is called or a round fails to send.
+// Handlers work without it, so a test or embedding program that drives
+// rounds itself with Tick need not call Start. A server runs at most once.
+func (s *Server) Start(ctx context.Context) error {
+	s.runMU.Lock()
+	defer s.runMU.Unlock()
+
+	switch {
+	case s.stopped:
+		return ErrStopped
+	case s.started:
+		return ErrAlreadyStarted
+	}
+	s.started = true
+
+	ctx, s.cancel = context.WithCancel(ctx)
+	s.loopDone = make(chan struct{})
+	go func() {
+		defer close(s.loopDone)
+		if err := s.loop(ctx); err != nil {
+			s.logs().gossip.Error("gossip loop stopped", "err", err)
+			s.runMU.Lock()
+			s.loopErr = err
+			s.runMU.Unlock()
+		}
+	}()
+	return nil
+}
+
+// loop calls Tick every GossipInterval until ctx is done or a round fails.
+func (s *Server) loop(ctx context.Context) error {
+	ticker := time.NewTicker(s.GossipInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := s.Tick(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Done returns a channel closed once the gossip loop has exited, whether
+// through Stop, its context or an error; see Err. Before Start it returns
+// nil, which blocks forever.
+func (s *Server) Done() <-chan struct{} {
+	s.runMU.Lock()
+	defer s.runMU.Unlock()
+
+	return s.loopDone
+}
+
+// Err returns the error that stopped the gossip loop, or nil if it is still
+// running or exited cleanly.
+func (s *Server) Err() error {
+	s.runMU.Lock()
+	defer s.runMU.Unlock()
+
+	return s.loopErr
+}
+
+// Stop shuts the server down: it ends the gossip loop and waits for it,
+// has every peer actor flush its pending batches, then stops the actors
+// and waits for them to exit. Later topology changes start no new actors.
+// Returns the loop's error, if any, joined with any error flushing. Calling
+// Stop again, or without Start, is safe.
+func (s *Server) Stop() error {
+	s.runMU.Lock()
+	if s.stopped {
+		err := s.loopErr
+		s.runMU.Unlock()
+		return err
+	}
+	s.stopped = true
+	cancel, done := s.cancel, s.loopDone
+	s.runMU.Unlock()
+
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+
+	s.peersMU.Lock()
+	s.peersClosed = true
+	peers := s.peerActors
+	s.peerActors = nil
+	s.peersMU.Unlock()
+
+	errs := []error{s.Err()}
+	for _, id := range slices.Sorted(maps.Keys(peers)) {
+		a := peers[id]
+		if err := a.call(flushMsg{}); !errors.Is(err, errPeerStopped) {
+			errs = append(errs, err)
+		}
+		a.stop()
+	}
+	for _, a := range peers {
+		<-a.exited
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,116 @@
+package gossip
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"maelstrom-broadcast/internal/protocol"
+	"maelstrom-broadcast/internal/sim"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingSend is a transport whose sends always fail, like a node whose
+// stdout has been closed.
+type failingSend struct {
+	*sim.Node
+}
+
+func (failingSend) Send(string, any) error {
+	return errors.New("stdout closed")
+}
+
+// waitDone waits for the server's gossip loop to exit.
+func waitDone(t *testing.T, s *Server) {
+	t.Helper()
+
+	select {
+	case <-s.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("gossip loop did not exit")
+	}
+}
+
+func TestServer_StartStop(t *testing.T) {
+	net := sim.NewNetwork(sim.Config{Seed: 41}, []string{"n0"})
+	s := NewServer(net.Node("n0"))
+	s.GossipInterval = time.Millisecond
+
+	require.NoError(t, s.Start(context.Background()))
+	assert.ErrorIs(t, s.Start(context.Background()), ErrAlreadyStarted)
+
+	assert.NoError(t, s.Stop())
+	waitDone(t, s)
+	assert.NoError(t, s.Stop(), "stopping twice is safe")
+	assert.ErrorIs(t, s.Start(context.Background()), ErrStopped)
+}
+
+func TestServer_ContextEndsLoop(t *testing.T) {
+	net := sim.NewNetwork(sim.Config{Seed: 43}, []string{"n0"})
+	s := NewServer(net.Node("n0"))
+	s.GossipInterval = time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	require.NoError(t, s.Start(ctx))
+	cancel()
+
+	waitDone(t, s)
+	assert.NoError(t, s.Err())
+	assert.NoError(t, s.Stop())
+}
+
+func TestServer_StopFlushesPendingBatches(t *testing.T) {
+	ids := []string{"n0", "n1"}
+	net := sim.NewNetwork(sim.Config{Seed: 47, Latency: time.Millisecond}, ids)
+	servers := make(map[string]*Server)
+	for _, id := range ids {
+		node := net.Node(id)
+		s := NewServer(node)
+		s.Clock = net
+		s.GossipMax = 10
+		s.Register(node)
+		servers[id] = s
+	}
+	net.Init()
+
+	var want []int
+	for i := 0; i < 35; i++ {
+		net.Inject("c1", "n0", map[string]any{"type": "broadcast", "message": i, "msg_id": i + 1})
+		want = append(want, i)
+	}
+	net.RunFor(time.Second)
+	require.Zero(t, servers["n1"].Messages.Len(), "nothing is sent without gossip rounds")
+
+	require.NoError(t, servers["n0"].Stop())
+	net.RunFor(time.Second)
+	assert.Equal(t, want, servers["n1"].Messages.Snapshot())
+
+	// A stopped server no longer gossips, even when its topology changes
+	net.Inject("c1", "n0", protocol.TopologyReq{Type: "topology"})
+	net.Inject("c1", "n0", map[string]any{"type": "broadcast", "message": 99, "msg_id": 100})
+	net.RunFor(time.Second)
+	assert.NoError(t, servers["n0"].Tick())
+	net.RunFor(time.Second)
+	assert.False(t, servers["n1"].Messages.Has(99))
+	assert.NoError(t, servers["n1"].Stop())
+}
+
+func TestServer_LoopErrorSurfaces(t *testing.T) {
+	net := sim.NewNetwork(sim.Config{Seed: 53}, []string{"n0", "n1"})
+	node := net.Node("n0")
+	s := NewServer(failingSend{node})
+	s.GossipInterval = time.Millisecond
+	s.Register(node)
+	net.Init()
+
+	net.Inject("c1", "n0", map[string]any{"type": "broadcast", "message": 1, "msg_id": 1})
+	net.RunFor(time.Second)
+
+	require.NoError(t, s.Start(context.Background()))
+	waitDone(t, s)
+	assert.ErrorContains(t, s.Err(), "stdout closed")
+	assert.ErrorContains(t, s.Stop(), "stdout closed")
+}
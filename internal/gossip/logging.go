@@ -0,0 +1,83 @@
+package gossip
+
+import (
+	// --- Standard Lib ---
+	"context"
+	"encoding/json"
+	"log/slog"
+
+	// --- Internal Lib ---
+	"maelstrom-broadcast/internal/logging"
+
+	// --- Third Party ---
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+)
+
+// loggers holds one logger per component, each tagged with the node ID.
+type loggers struct {
+	// handler traces incoming messages; debug records here are sampled
+	handler *slog.Logger
+
+	// gossip covers dissemination: neighbours, retransmits, anti-entropy
+	gossip *slog.Logger
+
+	// health reports failure detector transitions
+	health *slog.Logger
+
+	// wal reports write-ahead log problems
+	wal *slog.Logger
+}
+
+// nodeID defers reading the node ID until a record is written, since the
+// ID is only known after init but loggers are built before.
+type nodeID struct {
+	t Transport
+}
+
+// LogValue implements slog.LogValuer.
+func (n nodeID) LogValue() slog.Value {
+	return slog.StringValue(n.t.ID())
+}
+
+// logs returns the component loggers, deriving them from Logger on first use.
+func (s *Server) logs() *loggers {
+	s.logOnce.Do(func() {
+		base := s.Logger
+		if base == nil {
+			base = logging.Discard()
+		}
+		base = base.With("node", nodeID{s.Node})
+		s.loggers = &loggers{
+			handler: base.With(logging.ComponentKey, "handler"),
+			gossip:  base.With(logging.ComponentKey, "gossip"),
+			health:  base.With(logging.ComponentKey, "health"),
+			wal:     base.With(logging.ComponentKey, "wal"),
+		}
+	})
+	return s.loggers
+}
+
+// traced wraps a handler with request logging: a debug record per message
+// and a warning for every error reply, each carrying type, src and msg_id.
+func (s *Server) traced(typ string, fn maelstrom.HandlerFunc) maelstrom.HandlerFunc {
+	return func(msg maelstrom.Message) error {
+		l := s.logs().handler
+		if l.Enabled(context.Background(), slog.LevelDebug) {
+			l.Debug("recv", "type", typ, "src", msg.Src, "msg_id", msgID(msg))
+		}
+
+		err := fn(msg)
+		if err != nil {
+			l.Warn("error reply", "type", typ, "src", msg.Src, "msg_id", msgID(msg),
+				"code", errorReply(err).Code.String(), "err", err)
+		}
+		return err
+	}
+}
+
+// msgID extracts a message's msg_id for logging; 0 if it has none.
+func msgID(msg maelstrom.Message) int {
+	var body maelstrom.MessageBody
+	json.Unmarshal(msg.Body, &body)
+	return body.MsgID
+}
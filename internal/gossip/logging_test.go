@@ -0,0 +1,61 @@
+package gossip
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"maelstrom-broadcast/internal/logging"
+	"maelstrom-broadcast/internal/sim"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSim_LogsCarryNodeAndMsgID(t *testing.T) {
+	var buf bytes.Buffer
+	cfg, err := logging.ParseLevels("warn,handler=debug")
+	require.NoError(t, err)
+	cfg.JSON = true
+
+	net := sim.NewNetwork(sim.Config{Seed: 29}, []string{"n0"})
+	node := net.Node("n0")
+	s := NewServer(node)
+	s.Clock = net
+	s.Logger = logging.New(&buf, cfg)
+	s.Register(node)
+	net.Init()
+
+	net.Inject("c1", "n0", map[string]any{"type": "echo", "echo": "hi", "msg_id": 41})
+	net.Inject("c1", "n0", map[string]any{"type": "broadcast", "msg_id": 42})
+	net.RunFor(time.Second)
+
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var r map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &r))
+		records = append(records, r)
+	}
+
+	var sawRecv, sawError bool
+	for _, r := range records {
+		assert.Equal(t, "n0", r["node"])
+		assert.Equal(t, "handler", r["component"], "gossip info is below its warn level")
+		switch r["msg"] {
+		case "recv":
+			if r["msg_id"] == float64(41) {
+				sawRecv = true
+			}
+		case "error reply":
+			assert.Equal(t, slog.LevelWarn.String(), r["level"])
+			assert.Equal(t, float64(42), r["msg_id"])
+			assert.Equal(t, "malformed-request", r["code"])
+			sawError = true
+		}
+	}
+	assert.True(t, sawRecv, "debug trace for msg 41")
+	assert.True(t, sawError, "warning for msg 42")
+}
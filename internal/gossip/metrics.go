@@ -0,0 +1,246 @@
+package gossip
+
+import (
+	// --- Standard Lib ---
+	"context"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	// --- Internal Lib ---
+	"maelstrom-broadcast/internal/protocol"
+
+	// --- Third Party ---
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+)
+
+// maxFirstSeen bounds how many broadcast values Metrics keeps first-seen
+// times for; the oldest are evicted first.
+const maxFirstSeen = 10000
+
+// workloadOps lists the client request types that count as operations in
+// msgs-per-op, as Maelstrom counts them; setup messages such as init and
+// topology, and stats queries, are not operations.
+var workloadOps = map[string]bool{
+	"echo":                   true,
+	"generate":               true,
+	"broadcast":              true,
+	"read":                   true,
+	"add":                    true,
+	"send":                   true,
+	"poll":                   true,
+	"commit_offsets":         true,
+	"list_committed_offsets": true,
+	"txn":                    true,
+}
+
+// Metrics counts the traffic a Server generates so the challenge targets
+// (messages per operation, propagation latency) can be checked locally.
+type Metrics struct {
+	mu sync.Mutex
+
+	// sent and received count messages by body type
+	sent     map[string]uint64
+	received map[string]uint64
+
+	// clientOps counts workload requests from Maelstrom clients
+	clientOps uint64
+
+	// peerMessages counts messages sent to other nodes
+	peerMessages uint64
+
+	// batches summarizes fresh delta batches drained from peer queues
+	batches protocol.BatchStats
+
+	// retransmits counts deltas resent because no ack arrived in time
+	retransmits uint64
+
+	// firstSeen records when each broadcast value first reached this node,
+	// for at most seenLimit values; seenOrder is a ring of the values in
+	// arrival order and seenNext the slot the next one goes in, so the
+	// oldest is evicted first
+	firstSeen map[int]time.Time
+	seenOrder []int
+	seenNext  int
+	seenLimit int
+}
+
+// NewMetrics creates an empty metrics registry.
+func NewMetrics() *Metrics {
+	return &Metrics{
+		sent:      make(map[string]uint64),
+		received:  make(map[string]uint64),
+		firstSeen: make(map[int]time.Time),
+		seenLimit: maxFirstSeen,
+	}
+}
+
+// Sent counts an outgoing message of type typ to dest.
+func (m *Metrics) Sent(dest, typ string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sent[typ]++
+	if isNode(dest) {
+		m.peerMessages++
+	}
+}
+
+// Received counts an incoming message of type typ from src. Only workload
+// requests from clients count as operations.
+func (m *Metrics) Received(src, typ string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.received[typ]++
+	if isClient(src) && workloadOps[typ] {
+		m.clientOps++
+	}
+}
+
+// Transmitted records a delta transmission. attempt is 1 for a fresh batch,
+// whose size is recorded, and higher for retransmissions.
+func (m *Metrics) Transmitted(attempt, batchSize int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if attempt > 1 {
+		m.retransmits++
+		return
+	}
+	if batchSize == 0 {
+		return
+	}
+	m.batches.Count++
+	m.batches.Messages += uint64(batchSize)
+	if batchSize > m.batches.Max {
+		m.batches.Max = batchSize
+	}
+}
+
+// Seen records the first time this node saw a broadcast value. Once
+// seenLimit values are tracked, the oldest is forgotten to make room.
+func (m *Metrics) Seen(v int, at time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.firstSeen[v]; ok {
+		return
+	}
+	if len(m.seenOrder) < m.seenLimit {
+		m.seenOrder = append(m.seenOrder, v)
+	} else {
+		delete(m.firstSeen, m.seenOrder[m.seenNext])
+		m.seenOrder[m.seenNext] = v
+		m.seenNext = (m.seenNext + 1) % m.seenLimit
+	}
+	m.firstSeen[v] = at
+}
+
+// Snapshot returns a copy of the current metrics as a stats_ok body.
+func (m *Metrics) Snapshot() protocol.StatsOK {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	out := protocol.StatsOK{
+		Type:         "stats_ok",
+		Sent:         make(map[string]uint64, len(m.sent)),
+		Received:     make(map[string]uint64, len(m.received)),
+		ClientOps:    m.clientOps,
+		PeerMessages: m.peerMessages,
+		Batches:      m.batches,
+		Retransmits:  m.retransmits,
+		FirstSeen:    make(map[int]int64, len(m.firstSeen)),
+	}
+	for k, v := range m.sent {
+		out.Sent[k] = v
+	}
+	for k, v := range m.received {
+		out.Received[k] = v
+	}
+	for k, v := range m.firstSeen {
+		out.FirstSeen[k] = v.UnixMilli()
+	}
+	if m.clientOps > 0 {
+		out.MsgsPerOp = float64(m.peerMessages) / float64(m.clientOps)
+	}
+	return out
+}
+
+// HandleStats replies with a snapshot of this node's metrics.
+func (s *Server) HandleStats(msg maelstrom.Message) error {
+	return handle(msg, func(req protocol.StatsReq) error {
+		return s.Node.Reply(msg, s.Metrics.Snapshot())
+	})
+}
+
+// meteredTransport counts every message sent through the wrapped transport.
+type meteredTransport struct {
+	Transport
+	metrics *Metrics
+}
+
+// Send implements Transport.
+func (t *meteredTransport) Send(dest string, body any) error {
+	t.metrics.Sent(dest, bodyType(body))
+	return t.Transport.Send(dest, body)
+}
+
+// Reply implements Transport.
+func (t *meteredTransport) Reply(req maelstrom.Message, body any) error {
+	t.metrics.Sent(req.Src, bodyType(body))
+	return t.Transport.Reply(req, body)
+}
+
+// SyncRPC implements Transport.
+func (t *meteredTransport) SyncRPC(ctx context.Context, dest string, body any) (maelstrom.Message, error) {
+	t.metrics.Sent(dest, bodyType(body))
+	return t.Transport.SyncRPC(ctx, dest, body)
+}
+
+// metered wraps a handler so every message it receives is counted.
+func (s *Server) metered(typ string, fn maelstrom.HandlerFunc) maelstrom.HandlerFunc {
+	return func(msg maelstrom.Message) error {
+		s.Metrics.Received(msg.Src, typ)
+		return fn(msg)
+	}
+}
+
+// bodyType extracts the message type from an outgoing body without
+// marshaling it: protocol structs carry a Type field, forwarded bodies are
+// maps, and RPC errors always have type "error".
+func bodyType(body any) string {
+	switch b := body.(type) {
+	case *maelstrom.RPCError:
+		return "error"
+	case map[string]any:
+		if t, ok := b["type"].(string); ok {
+			return t
+		}
+		return ""
+	}
+
+	v := reflect.ValueOf(body)
+	if v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return ""
+	}
+	if f := v.FieldByName("Type"); f.IsValid() && f.Kind() == reflect.String {
+		return f.String()
+	}
+	return ""
+}
+
+// isClient reports whether id names a Maelstrom client.
+func isClient(id string) bool {
+	return strings.HasPrefix(id, "c")
+}
+
+// isNode reports whether id names a cluster node rather than a client or a
+// built-in service such as lin-kv.
+func isNode(id string) bool {
+	return strings.HasPrefix(id, "n") && !strings.Contains(id, "-")
+}
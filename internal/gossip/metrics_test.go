@@ -0,0 +1,100 @@
+package gossip
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"maelstrom-broadcast/internal/protocol"
+	"maelstrom-broadcast/internal/sim"
+	"maelstrom-broadcast/internal/topology"
+
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBodyType(t *testing.T) {
+	assert.Equal(t, "delta", bodyType(protocol.DeltaReq{Type: "delta"}))
+	assert.Equal(t, "delta_ok", bodyType(&protocol.DeltaOK{Type: "delta_ok"}))
+	assert.Equal(t, "send", bodyType(map[string]any{"type": "send"}))
+	assert.Equal(t, "error", bodyType(maelstrom.NewRPCError(maelstrom.Crash, "boom")))
+	assert.Equal(t, "", bodyType(42))
+}
+
+func TestMetrics_Snapshot(t *testing.T) {
+	m := NewMetrics()
+	m.Received("c1", "broadcast")
+	m.Received("c2", "broadcast")
+	m.Received("n1", "delta")
+	m.Received("c1", "init")
+	m.Received("c1", "topology")
+	m.Sent("n1", "delta")
+	m.Sent("n2", "delta")
+	m.Sent("n3", "delta")
+	m.Sent("c1", "broadcast_ok")
+	m.Sent("lin-kv", "read")
+
+	m.Transmitted(1, 3)
+	m.Transmitted(1, 5)
+	m.Transmitted(2, 5)
+	m.Seen(7, time.UnixMilli(100))
+	m.Seen(7, time.UnixMilli(200))
+
+	snap := m.Snapshot()
+	assert.Equal(t, uint64(2), snap.ClientOps)
+	assert.Equal(t, uint64(3), snap.PeerMessages)
+	assert.InDelta(t, 1.5, snap.MsgsPerOp, 1e-9)
+	assert.Equal(t, uint64(3), snap.Sent["delta"])
+	assert.Equal(t, uint64(2), snap.Received["broadcast"])
+	assert.Equal(t, protocol.BatchStats{Count: 2, Messages: 8, Max: 5}, snap.Batches)
+	assert.Equal(t, uint64(1), snap.Retransmits)
+	assert.Equal(t, int64(100), snap.FirstSeen[7])
+}
+
+func TestMetrics_FirstSeenEvictsOldest(t *testing.T) {
+	m := NewMetrics()
+	m.seenLimit = 3
+	for v := 0; v < 5; v++ {
+		m.Seen(v, time.UnixMilli(int64(v)))
+	}
+	m.Seen(4, time.UnixMilli(99))
+
+	assert.Equal(t, map[int]int64{2: 2, 3: 3, 4: 4}, m.Snapshot().FirstSeen)
+}
+
+func TestSim_StatsMessage(t *testing.T) {
+	c := newCluster(t, sim.Config{Seed: 17, Latency: 100 * time.Millisecond}, 5, topology.Mesh{})
+	c.topology()
+	want := c.broadcast(10, 10*time.Millisecond)
+	require.True(t, c.net.RunUntil(func() bool { return c.converged(want) }, 5*time.Second))
+
+	c.inject("n0", protocol.StatsReq{Type: "stats"})
+	c.net.RunFor(time.Second)
+
+	var stats protocol.StatsOK
+	for _, r := range c.net.Replies("c1") {
+		if r.Type() == "stats_ok" {
+			require.NoError(t, json.Unmarshal(r.Body, &stats))
+		}
+	}
+	assert.Equal(t, "stats_ok", stats.Type)
+	assert.Greater(t, stats.ClientOps, uint64(0))
+	assert.Greater(t, stats.Sent["delta"], uint64(0))
+	assert.Len(t, stats.FirstSeen, 10)
+
+	// Every value must have reached every node within a few network hops
+	for v := range want {
+		first, last := int64(-1), int64(-1)
+		for _, s := range c.servers {
+			seen := s.Metrics.Snapshot().FirstSeen[v]
+			if first < 0 || seen < first {
+				first = seen
+			}
+			if seen > last {
+				last = seen
+			}
+		}
+		assert.LessOrEqual(t, last-first, int64(600), "value %d", v)
+	}
+}
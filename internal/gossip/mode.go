@@ -0,0 +1,35 @@
+package gossip
+
+import (
+	// --- Standard Lib ---
+	"fmt"
+)
+
+// Mode selects how broadcast values are disseminated.
+type Mode string
+
+const (
+	// DeltaMode queues every new value for every overlay neighbour and
+	// retransmits each batch until it is acknowledged
+	DeltaMode Mode = "delta"
+
+	// PushPullMode spreads values as rumors: each round a few random live
+	// neighbours are sent the hot rumors and answer with their own, and a
+	// rumor stops spreading once enough peers report already knowing it
+	PushPullMode Mode = "push-pull"
+
+	// PlumtreeMode sends new values as deltas along a spanning tree of the
+	// overlay and only announces them over the remaining links, grafting a
+	// link back into the tree when an announced value fails to arrive
+	PlumtreeMode Mode = "plumtree"
+)
+
+// ParseMode maps a mode name ("delta", "push-pull" or "plumtree") to a Mode.
+func ParseMode(name string) (Mode, error) {
+	switch m := Mode(name); m {
+	case DeltaMode, PushPullMode, PlumtreeMode:
+		return m, nil
+	default:
+		return "", fmt.Errorf("gossip: unknown mode %q", name)
+	}
+}
@@ -0,0 +1,382 @@
+package gossip
+
+import (
+	// --- Standard Lib ---
+	"errors"
+	"maps"
+	"sync"
+	"time"
+
+	// --- Internal Lib ---
+	"maelstrom-broadcast/internal/protocol"
+	"maelstrom-broadcast/internal/queue"
+	"maelstrom-broadcast/internal/versions"
+)
+
+// peerInbox is how many requests may wait for a peer actor before senders
+// block, which bounds the memory a slow peer can pin.
+const peerInbox = 1024
+
+// errPeerStopped is returned by call once the actor has stopped, such as
+// after a topology change dropped the peer.
+var errPeerStopped = errors.New("gossip: peer actor stopped")
+
+// Requests a peer actor understands.
+type (
+	// enqueueMsg queues untagged values that the peer has yet to be sent
+	enqueueMsg struct{ values []int }
+
+	// haveMsg records the version vector the peer sent with a delta
+	haveMsg struct{ have versions.Vector }
+
+	// tickMsg runs one gossip round for the peer at now
+	tickMsg struct{ now time.Time }
+
+	// ackMsg applies a delta_ok received from the peer at now
+	ackMsg struct {
+		resp protocol.DeltaOK
+		now  time.Time
+	}
+
+	// resetMsg clears the retry backoff so the next delta goes out at once
+	resetMsg struct{}
+
+	// flushMsg sends everything still queued for the peer
+	flushMsg struct{}
+
+	// dropMsg discards the queued values and the batch in flight, for a
+	// peer that no longer needs values pushed; its version vector is kept
+	// and stays current through its own deltas and announcements
+	dropMsg struct{}
+
+	// vectorMsg copies the peer's version vector into out, nil if unknown
+	vectorMsg struct{ out *versions.Vector }
+)
+
+// peerRequest carries one request to a peer actor; done, if set, receives
+// the outcome once the actor has handled it.
+type peerRequest struct {
+	msg  any
+	done chan error
+}
+
+// peerActor owns the gossip state for one neighbour: its version vector, the
+// queue of untagged values it has yet to be sent, the batch in flight to it
+// and the retry timer for that batch. Only the actor's goroutine touches that
+// state; handlers and the gossip loop talk to it through its inbox, so they
+// never race with a round.
+type peerActor struct {
+	id string
+	s  *Server
+
+	// pq is the peer's queue and delivery state, owned by run
+	pq *queue.Peer
+
+	// have is the peer's version vector as last reported by the peer, or
+	// nil until it is known; owned by run
+	have versions.Vector
+
+	// skip holds the trimmed prefixes the peer has yet to be told to skip;
+	// owned by run
+	skip versions.Vector
+
+	// inbox feeds run in arrival order; quit asks run to stop and exited
+	// is closed once it has
+	inbox    chan peerRequest
+	quit     chan struct{}
+	exited   chan struct{}
+	stopOnce sync.Once
+}
+
+// newPeerActor starts an actor for neighbour id. Its first delta carries no
+// entries and only asks for the peer's version vector.
+func newPeerActor(s *Server, id string) *peerActor {
+	a := &peerActor{
+		id:     id,
+		s:      s,
+		pq:     queue.NewPeerQueue(),
+		inbox:  make(chan peerRequest, peerInbox),
+		quit:   make(chan struct{}),
+		exited: make(chan struct{}),
+	}
+	go a.run()
+	return a
+}
+
+// cast hands msg to the actor without waiting for it to be handled.
+// Requests from one goroutine are handled in the order they were cast.
+// Returns false if the actor has stopped.
+func (a *peerActor) cast(msg any) bool {
+	if a.stopped() {
+		return false
+	}
+	select {
+	case a.inbox <- peerRequest{msg: msg}:
+		return true
+	case <-a.exited:
+		return false
+	}
+}
+
+// call hands msg to the actor and waits until it has been handled, so any
+// messages the actor sends in response go out before call returns. Returns
+// the error sending them, or errPeerStopped if the actor stopped first.
+func (a *peerActor) call(msg any) error {
+	if a.stopped() {
+		return errPeerStopped
+	}
+	done := make(chan error, 1)
+	select {
+	case a.inbox <- peerRequest{msg: msg, done: done}:
+	case <-a.exited:
+		return errPeerStopped
+	}
+	select {
+	case err := <-done:
+		return err
+	case <-a.exited:
+		return errPeerStopped
+	}
+}
+
+// stop asks the actor to exit once it finishes the request in hand.
+// Requests still in its inbox are dropped.
+func (a *peerActor) stop() {
+	a.stopOnce.Do(func() { close(a.quit) })
+}
+
+// stopped reports whether the actor's goroutine has exited. A request
+// queued just as it exits is dropped rather than handled.
+func (a *peerActor) stopped() bool {
+	select {
+	case <-a.exited:
+		return true
+	default:
+		return false
+	}
+}
+
+// run is the actor's goroutine. After each request the failure detector is
+// told whether a delta to the peer is still unacknowledged, since only such
+// peers are probed.
+func (a *peerActor) run() {
+	defer close(a.exited)
+	for {
+		select {
+		case <-a.quit:
+			return
+		case req := <-a.inbox:
+			err := a.handle(req.msg)
+			a.s.Health.Await(a.id, a.pq.Attempts > 0, a.s.Clock.Now())
+			if req.done != nil {
+				req.done <- err
+			}
+		}
+	}
+}
+
+// handle applies a single request to the actor's state. Returns the error,
+// if any, from sending the peer a message in response.
+func (a *peerActor) handle(msg any) error {
+	switch m := msg.(type) {
+	case enqueueMsg:
+		a.pq.AddAll(m.values...)
+	case haveMsg:
+		a.learn(m.have)
+	case tickMsg:
+		return a.tick(m.now)
+	case ackMsg:
+		return a.ack(m.resp, m.now)
+	case resetMsg:
+		a.pq.ResetRetry()
+	case flushMsg:
+		return a.flush()
+	case dropMsg:
+		a.pq.Clear()
+		a.pq.Ack(a.pq.InFlightID)
+		a.pq.ResetRetry()
+	case vectorMsg:
+		if a.have != nil {
+			*m.out = maps.Clone(a.have)
+		}
+	}
+	return nil
+}
+
+// learn merges a version vector reported by the peer into what it is known
+// to hold. An empty vector still counts as knowing the peer holds nothing.
+// Skips the vector shows were applied are no longer sent. Reports whether
+// that moved anything forward.
+func (a *peerActor) learn(have versions.Vector) bool {
+	moved := a.have == nil
+	if moved {
+		a.have = make(versions.Vector, len(have))
+	}
+	for origin, seq := range a.skip {
+		if have[origin] >= seq {
+			delete(a.skip, origin)
+		}
+	}
+	return a.have.Merge(have) || moved
+}
+
+// entries returns up to limit tagged entries the peer lacks, or none if
+// values are not pushed to it or its version vector is not known yet. A
+// limit of zero means no limit. hello reports that the vector is needed
+// first, so the next delta should go out even if it is otherwise empty.
+// A peer that lacks entries already trimmed from the log, which only a
+// neighbour added after the trim can, is queued every value untagged
+// instead and told to skip past them.
+func (a *peerActor) entries(limit int) (entries []protocol.Entry, hello bool) {
+	s := a.s
+	if !s.pushesTo(a.id) {
+		return nil, false
+	}
+	if a.have == nil {
+		return nil, true
+	}
+	if behind := s.Versions.Behind(a.have); behind != nil {
+		s.logs().gossip.Debug("peer behind trimmed log", "peer", a.id, "skip", behind)
+		a.pq.AddAll(s.Messages.GetSlice()...)
+		a.have.Merge(behind)
+		if a.skip == nil {
+			a.skip = make(versions.Vector, len(behind))
+		}
+		a.skip.Merge(behind)
+	}
+	return s.Versions.Since(a.have, limit), false
+}
+
+// unacked returns the counter state and transaction writes the peer has
+// yet to acknowledge, or nils if it is up to date.
+func (a *peerActor) unacked() (counters map[string]int, version uint64, txns []protocol.TxnWrites, upto int) {
+	s := a.s
+	if v := s.Counters.Version(); a.pq.CounterBehind(v) {
+		counters, version = s.Counters.Snapshot()
+	}
+	txns, upto = s.Txns.Since(a.pq.TxnFrom(), s.GossipMax)
+	return counters, version, txns, upto
+}
+
+// tick sends the peer a delta carrying the entries its version vector lacks,
+// its next batch of untagged values and any counter state and transaction
+// writes it has not acknowledged. A delta still awaiting acknowledgment is
+// only resent once its backoff has passed, and nothing is sent to a peer
+// the failure detector considers dead.
+func (a *peerActor) tick(now time.Time) error {
+	s := a.s
+	if !s.reachable(a.id) {
+		return nil
+	}
+
+	entries, hello := a.entries(s.GossipMax)
+	batchID, batch := a.pq.NextBatch(s.GossipMax)
+	counters, version, txns, upto := a.unacked()
+
+	if len(entries) == 0 && !hello && len(batch) == 0 && len(a.skip) == 0 && counters == nil && txns == nil {
+		// Nothing is unacknowledged any more, even if the last ack moved nothing
+		a.pq.ResetRetry()
+		return nil
+	}
+	if !a.pq.RetryDue(now) {
+		return nil
+	}
+
+	err := s.Node.Send(a.id, protocol.DeltaReq{
+		Type:           "delta",
+		BatchID:        batchID,
+		Messages:       batch,
+		Entries:        entries,
+		Have:           s.Versions.Vector(),
+		Skip:           a.skip,
+		Counters:       counters,
+		CounterVersion: version,
+		Txns:           txns,
+		TxnUpto:        upto,
+	})
+	attempt := a.pq.MarkSent(now, s.retry().Backoff)
+	if attempt > 1 {
+		s.logs().gossip.Debug("retransmit", "peer", a.id, "batch_id", batchID, "attempt", attempt)
+	}
+	s.Metrics.Transmitted(attempt, len(batch)+len(entries))
+	return err
+}
+
+// ack records what the peer acknowledged, including its version vector.
+// Only an ack that retires the batch in flight or moves one of the peer's
+// watermarks forward clears the backoff; a stale or duplicate ack leaves the
+// pending retransmission on schedule. Whatever the peer still lacks is then
+// sent straight away, unless a batch of untagged values is still in flight
+// and this ack does not name it; that batch's own ack or retry sends the
+// next delta.
+func (a *peerActor) ack(resp protocol.DeltaOK, now time.Time) error {
+	moved := a.learn(resp.Have)
+	moved = a.pq.AckCounter(resp.CounterVersion) || moved
+	moved = a.pq.AckTxn(resp.TxnUpto) || moved
+	retired := a.pq.Ack(resp.BatchID)
+	if !retired && !moved {
+		return nil
+	}
+	a.pq.ResetRetry()
+
+	if !retired && a.pq.InFlight != nil {
+		return nil
+	}
+
+	s := a.s
+	entries, _ := a.entries(s.GossipMax)
+	id, next := a.pq.NextBatch(s.GossipMax)
+	if len(entries) == 0 && len(next) == 0 {
+		return nil
+	}
+	err := s.Node.Send(a.id, protocol.DeltaReq{
+		Type:     "delta",
+		BatchID:  id,
+		Messages: next,
+		Entries:  entries,
+		Have:     s.Versions.Vector(),
+		Skip:     a.skip,
+	})
+	s.Metrics.Transmitted(a.pq.MarkSent(now, s.retry().Backoff), len(next)+len(entries))
+	return err
+}
+
+// flush sends the in-flight batch, everything still queued and every entry
+// the peer's version vector lacks, or every entry if the vector is not known
+// yet, GossipMax values per delta, without waiting for acks or backoff;
+// unacknowledged counter state and transaction writes ride along with the
+// first delta. Used on shutdown, when no more
+// rounds will run. The deltas carry no batch ID, so their acks are ignored.
+func (a *peerActor) flush() error {
+	s := a.s
+	if !s.reachable(a.id) {
+		return nil
+	}
+
+	_, batch := a.pq.NextBatch(s.GossipMax)
+	a.pq.Ack(a.pq.InFlightID)
+	pending, hello := a.entries(0)
+	if hello {
+		pending = s.Versions.Since(nil, 0)
+	}
+	counters, version, txns, upto := a.unacked()
+
+	var errs []error
+	for len(batch) > 0 || len(pending) > 0 || counters != nil || txns != nil {
+		entries := pending[:min(len(pending), s.GossipMax)]
+		pending = pending[len(entries):]
+		errs = append(errs, s.Node.Send(a.id, protocol.DeltaReq{
+			Type:           "delta",
+			Messages:       batch,
+			Entries:        entries,
+			Skip:           a.skip,
+			Counters:       counters,
+			CounterVersion: version,
+			Txns:           txns,
+			TxnUpto:        upto,
+		}))
+		s.Metrics.Transmitted(1, len(batch)+len(entries))
+		batch, counters, txns = a.pq.DrainN(s.GossipMax), nil, nil
+	}
+	return errors.Join(errs...)
+}
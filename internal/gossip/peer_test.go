@@ -0,0 +1,104 @@
+package gossip
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"maelstrom-broadcast/internal/protocol"
+	"maelstrom-broadcast/internal/sim"
+
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestActor(t *testing.T) (*sim.Network, *peerActor) {
+	t.Helper()
+
+	net := sim.NewNetwork(sim.Config{Seed: 37}, []string{"n0", "n1"})
+	s := NewServer(net.Node("n0"))
+	s.Clock = net
+	s.Versions.SetOrigin("n0.0")
+	a := newPeerActor(s, "n1")
+	t.Cleanup(a.stop)
+	return net, a
+}
+
+func TestPeerActor_SendsOnlyWhatPeerVectorLacks(t *testing.T) {
+	net, a := newTestActor(t)
+	for _, v := range []int{1, 2, 3} {
+		a.s.Versions.Append(v)
+	}
+
+	var got []protocol.DeltaReq
+	net.Node("n1").Handle("delta", func(msg maelstrom.Message) error {
+		var req protocol.DeltaReq
+		require.NoError(t, json.Unmarshal(msg.Body, &req))
+		got = append(got, req)
+		return nil
+	})
+
+	// The first delta only asks for the peer's vector
+	require.NoError(t, a.call(tickMsg{now: net.Now()}))
+	net.RunFor(time.Millisecond)
+	require.Len(t, got, 1)
+	assert.Empty(t, got[0].Entries)
+	assert.Equal(t, map[string]uint64{"n0.0": 3}, got[0].Have)
+
+	// A tick before the backoff passes resends nothing
+	require.NoError(t, a.call(tickMsg{now: net.Now()}))
+	net.RunFor(time.Millisecond)
+	assert.Len(t, got, 1)
+
+	require.NoError(t, a.call(ackMsg{resp: protocol.DeltaOK{Have: map[string]uint64{"n0.0": 1}}, now: net.Now()}))
+	net.RunFor(time.Millisecond)
+	require.Len(t, got, 2, "ack sends the missing entries straight away")
+	assert.Equal(t, []protocol.Entry{{Origin: "n0.0", Seq: 2, Value: 2}, {Origin: "n0.0", Seq: 3, Value: 3}}, got[1].Entries)
+
+	require.True(t, a.cast(enqueueMsg{values: []int{4}}))
+	require.NoError(t, a.call(ackMsg{resp: protocol.DeltaOK{Have: map[string]uint64{"n0.0": 3}}, now: net.Now()}))
+	net.RunFor(time.Millisecond)
+	require.Len(t, got, 3)
+	assert.Empty(t, got[2].Entries)
+	assert.Equal(t, []int{4}, got[2].Messages)
+}
+
+func TestPeerActor_StaleAckKeepsBackoff(t *testing.T) {
+	net, a := newTestActor(t)
+	a.s.Retry = fixedPolicy(time.Second)
+
+	var got []protocol.DeltaReq
+	net.Node("n1").Handle("delta", func(msg maelstrom.Message) error {
+		var req protocol.DeltaReq
+		require.NoError(t, json.Unmarshal(msg.Body, &req))
+		got = append(got, req)
+		return nil
+	})
+
+	require.True(t, a.cast(haveMsg{}))
+	require.True(t, a.cast(enqueueMsg{values: []int{1}}))
+	require.NoError(t, a.call(tickMsg{now: net.Now()}))
+	require.True(t, a.cast(enqueueMsg{values: []int{2}}))
+	require.NoError(t, a.call(ackMsg{resp: protocol.DeltaOK{BatchID: 1}, now: net.Now()}))
+	net.RunFor(time.Millisecond)
+	require.Len(t, got, 2)
+	assert.Equal(t, uint64(2), got[1].BatchID)
+
+	// A duplicate of the first ack must not clear the second batch's backoff
+	require.NoError(t, a.call(ackMsg{resp: protocol.DeltaOK{BatchID: 1}, now: net.Now()}))
+	require.NoError(t, a.call(tickMsg{now: net.Now()}))
+	net.RunFor(time.Millisecond)
+	assert.Len(t, got, 2, "stale ack brought the retransmission forward")
+}
+
+func TestPeerActor_StoppedActorRejectsRequests(t *testing.T) {
+	_, a := newTestActor(t)
+
+	a.stop()
+	a.stop()
+	<-a.exited
+
+	assert.False(t, a.cast(enqueueMsg{values: []int{4}}))
+	assert.ErrorIs(t, a.call(tickMsg{}), errPeerStopped)
+}
@@ -0,0 +1,171 @@
+package gossip
+
+import (
+	// --- Standard Lib ---
+	"errors"
+	"maps"
+	"slices"
+	"time"
+
+	// --- Internal Lib ---
+	"maelstrom-broadcast/internal/protocol"
+
+	// --- Third Party ---
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+)
+
+// lazyRound announces new values to the lazy neighbours every
+// IHaveInterval, and every round grafts the neighbours whose announced
+// values failed to arrive within GraftTimeout.
+func (s *Server) lazyRound(now time.Time) error {
+	var errs []error
+	if s.ihaveDue(now) {
+		errs = append(errs, s.announce())
+	}
+
+	due := s.tree.Due(now)
+	for _, peer := range slices.Sorted(maps.Keys(due)) {
+		var values []int
+		for _, v := range due[peer] {
+			if !s.Messages.Has(v) {
+				values = append(values, v)
+			}
+		}
+		if len(values) == 0 {
+			continue
+		}
+		s.tree.Graft(peer)
+		s.logs().gossip.Debug("graft", "peer", peer, "missing", len(values))
+		errs = append(errs, s.Node.Send(peer, protocol.GraftReq{Type: "graft", Messages: values}))
+	}
+	return errors.Join(errs...)
+}
+
+// announce sends the values learned since the last announcement to every
+// reachable lazy neighbour, skipping each value's sender.
+func (s *Server) announce() error {
+	ann := s.tree.Announcements()
+	if len(ann) == 0 {
+		return nil
+	}
+
+	var errs []error
+	for _, peer := range s.tree.Lazy() {
+		if !s.reachable(peer) {
+			continue
+		}
+		var values []int
+		for _, a := range ann {
+			if a.From != peer {
+				values = append(values, a.Value)
+			}
+		}
+		if len(values) > 0 {
+			errs = append(errs, s.Node.Send(peer, protocol.IHaveReq{
+				Type:     "ihave",
+				Messages: values,
+				Have:     s.Versions.Vector(),
+			}))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ihaveDue reports whether an announcement round is due at now, and if so
+// schedules the next one.
+func (s *Server) ihaveDue(now time.Time) bool {
+	s.ihaveMU.Lock()
+	defer s.ihaveMU.Unlock()
+
+	if now.Before(s.ihaveAt) {
+		return false
+	}
+	s.ihaveAt = now.Add(s.IHaveInterval)
+	return true
+}
+
+// treeDelivered updates the tree after a delta from src carrying fresh new
+// values and dup duplicates that src had not delivered before. New values
+// mean src is on this node's tree, so it is made eager; a delta of nothing
+// but such duplicates means the link is redundant, so it is pruned at both
+// ends. Values src merely resent after a lost ack are not counted in dup. The prune is sent even if src was already
+// lazy here, since src evidently still pushes eagerly; a lost prune only
+// costs more duplicates, which prune the link again. Returns the error
+// sending the prune.
+func (s *Server) treeDelivered(src string, fresh, dup int) error {
+	if fresh > 0 {
+		s.tree.Graft(src)
+		return nil
+	}
+	if dup == 0 {
+		return nil
+	}
+	s.prune(src)
+	s.logs().gossip.Debug("prune", "peer", src)
+	return s.Node.Send(src, protocol.PruneReq{Type: "prune"})
+}
+
+// prune moves peer to the lazy side and drops the values queued for it, so
+// they are not pushed as duplicates; lazy announcements and digests cover
+// anything it still lacks. The peer's version vector is kept, so a graft
+// resumes from what it holds.
+func (s *Server) prune(peer string) {
+	s.tree.Prune(peer)
+	if a, ok := s.peer(peer); ok {
+		a.cast(dropMsg{})
+	}
+}
+
+// HandleIHave notes the announced values this node lacks. Any still missing
+// after GraftTimeout are grafted from the announcer by the next lazy round.
+// The announcer's version vector is handed to its actor, so a lazy peer
+// does not hold back trimming the version log.
+func (s *Server) HandleIHave(msg maelstrom.Message) error {
+	return handle(msg, func(req protocol.IHaveReq) error {
+		s.observe(msg.Src)
+		if a, ok := s.peer(msg.Src); ok && req.Have != nil {
+			a.cast(haveMsg{have: req.Have})
+		}
+
+		var lacking []int
+		for _, v := range req.Messages {
+			if !s.Messages.Has(v) {
+				lacking = append(lacking, v)
+			}
+		}
+		if len(lacking) > 0 {
+			s.tree.IHave(msg.Src, lacking, s.Clock.Now().Add(s.GraftTimeout))
+		}
+		return nil
+	})
+}
+
+// HandleGraft puts the sender back on the eager side and queues the values
+// it asked for, which travel as ordinary acknowledged deltas.
+func (s *Server) HandleGraft(msg maelstrom.Message) error {
+	return handle(msg, func(req protocol.GraftReq) error {
+		s.observe(msg.Src)
+		s.tree.Graft(msg.Src)
+
+		var values []int
+		for _, v := range req.Messages {
+			if s.Messages.Has(v) {
+				values = append(values, v)
+			}
+		}
+		if len(values) > 0 {
+			s.resend(msg.Src, values)
+		}
+		return nil
+	})
+}
+
+// HandlePrune moves the sender to the lazy side: it already receives new
+// values through another path.
+func (s *Server) HandlePrune(msg maelstrom.Message) error {
+	return handle(msg, func(protocol.PruneReq) error {
+		s.observe(msg.Src)
+		s.prune(msg.Src)
+		return nil
+	})
+}
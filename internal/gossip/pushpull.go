@@ -0,0 +1,131 @@
+package gossip
+
+import (
+	// --- Standard Lib ---
+	"errors"
+	"math/rand/v2"
+	"slices"
+	"time"
+
+	// --- Internal Lib ---
+	"maelstrom-broadcast/internal/protocol"
+	"maelstrom-broadcast/internal/rumor"
+
+	// --- Third Party ---
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+)
+
+// SeedFanout makes the choice of push-pull targets reproducible.
+func (s *Server) SeedFanout(seed uint64) {
+	s.fanoutMU.Lock()
+	defer s.fanoutMU.Unlock()
+
+	s.fanoutRNG = rand.New(rand.NewPCG(seed, seed))
+}
+
+// pushRound pushes the hot rumors, if there are any, to Fanout random live
+// neighbours among ids. Every PullInterval it also sends an empty push to
+// one random live neighbour not pushed to this round; the reply pulls that
+// peer's hot rumors, so values held by a peer that has stopped pushing still
+// spread. Values rumor mongering missed altogether are left to the digest
+// exchange.
+func (s *Server) pushRound(now time.Time, ids []string) error {
+	hot := s.rumors.Hot(s.GossipMax)
+
+	live := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if s.reachable(id) {
+			live = append(live, id)
+		}
+	}
+	s.fanoutMU.Lock()
+	var targets, pulls []string
+	if len(hot) > 0 {
+		targets = rumor.Sample(s.fanoutRNG, live, s.Fanout)
+	}
+	if s.PullInterval > 0 && !now.Before(s.pullAt) {
+		s.pullAt = now.Add(s.PullInterval)
+		rest := slices.DeleteFunc(slices.Clone(live), func(id string) bool { return slices.Contains(targets, id) })
+		pulls = rumor.Sample(s.fanoutRNG, rest, 1)
+	}
+	s.fanoutMU.Unlock()
+
+	var errs []error
+	for _, id := range targets {
+		errs = append(errs, s.Node.Send(id, protocol.PushReq{Type: "push", Messages: hot}))
+		s.Metrics.Transmitted(1, len(hot))
+	}
+	for _, id := range pulls {
+		errs = append(errs, s.Node.Send(id, protocol.PushReq{Type: "push", Messages: []int{}}))
+	}
+	return errors.Join(errs...)
+}
+
+// HandlePush takes in a peer's hot rumors. New values are stored and become
+// hot here; the reply reports which values were already known, so the
+// sender can retire them, and carries this node's other hot rumors back.
+func (s *Server) HandlePush(msg maelstrom.Message) error {
+	return handle(msg, func(req protocol.PushReq) error {
+		s.observe(msg.Src)
+
+		pushed := make(map[int]bool, len(req.Messages))
+		var known []int
+		for _, v := range req.Messages {
+			pushed[v] = true
+			added, err := s.addMessage(v, msg.Src)
+			if err != nil {
+				return err
+			}
+			if !added {
+				known = append(known, v)
+			}
+		}
+
+		var pull []int
+		for _, v := range s.rumors.Hot(s.GossipMax) {
+			if !pushed[v] {
+				pull = append(pull, v)
+			}
+		}
+		return s.Node.Reply(msg, protocol.PushOK{
+			Type:     "push_ok",
+			Known:    known,
+			Messages: pull,
+		})
+	})
+}
+
+// HandlePushOK applies a push reply: rumors the peer already knew move
+// towards retirement, and the peer's hot rumors are stored like any others.
+// Pulled values that were already known are reported back in a pull_ok, so
+// the peer's rumors retire too.
+func (s *Server) HandlePushOK(msg maelstrom.Message) error {
+	return handle(msg, func(resp protocol.PushOK) error {
+		s.observe(msg.Src)
+		s.rumors.Feedback(resp.Known, s.RumorLimit)
+		var known []int
+		for _, v := range resp.Messages {
+			added, err := s.addMessage(v, msg.Src)
+			if err != nil {
+				return err
+			}
+			if !added {
+				known = append(known, v)
+			}
+		}
+		if len(known) == 0 {
+			return nil
+		}
+		return s.Node.Send(msg.Src, protocol.PullOK{Type: "pull_ok", Known: known})
+	})
+}
+
+// HandlePullOK retires this node's rumors that a peer pulled but already
+// knew, the feedback a push reply carries for pushed values.
+func (s *Server) HandlePullOK(msg maelstrom.Message) error {
+	return handle(msg, func(resp protocol.PullOK) error {
+		s.observe(msg.Src)
+		s.rumors.Feedback(resp.Known, s.RumorLimit)
+		return nil
+	})
+}
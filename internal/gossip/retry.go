@@ -1 +1,195 @@
 package gossip
+
+import (
+	// --- Standard Lib ---
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// Clock abstracts the current time so retry scheduling can be driven by a
+// fake clock in tests.
+type Clock interface {
+	Now() time.Time
+}
+
+// realClock reads the system clock.
+type realClock struct{}
+
+// Now implements Clock.
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+// RetryPolicy decides how long to wait before retransmitting an
+// unacknowledged delta to a peer.
+type RetryPolicy interface {
+	// Backoff returns the delay to wait after the attempt-th transmission
+	// (1-based). prev is the delay returned for the previous attempt, or
+	// zero on the first.
+	Backoff(attempt int, prev time.Duration) time.Duration
+}
+
+// Jitter selects how ExponentialBackoff randomizes its delays.
+type Jitter int
+
+const (
+	// NoJitter uses the raw exponential delay
+	NoJitter Jitter = iota
+
+	// FullJitter picks uniformly in [0, exponential delay), so a retry may
+	// fire well before an ack could arrive
+	FullJitter
+
+	// DecorrelatedJitter picks uniformly in [Base, 3*prev), so delays grow
+	// without lockstep between peers that failed at the same moment
+	DecorrelatedJitter
+
+	// EqualJitter keeps half the exponential delay and randomizes the other
+	// half, picking uniformly in [delay/2, delay)
+	EqualJitter
+)
+
+// ParseJitter maps a jitter name ("none", "full", "equal" or "decorrelated")
+// to a Jitter.
+func ParseJitter(name string) (Jitter, error) {
+	switch name {
+	case "none":
+		return NoJitter, nil
+	case "full":
+		return FullJitter, nil
+	case "equal":
+		return EqualJitter, nil
+	case "decorrelated":
+		return DecorrelatedJitter, nil
+	default:
+		return 0, fmt.Errorf("gossip: unknown jitter %q", name)
+	}
+}
+
+// ExponentialBackoff doubles the delay on every attempt up to Cap, randomized
+// according to Jitter so partitioned peers don't all retry in the same tick.
+type ExponentialBackoff struct {
+	// Base is the delay after the first attempt, before jitter
+	Base time.Duration
+
+	// Cap bounds every delay returned
+	Cap time.Duration
+
+	// Jitter selects the randomization strategy
+	Jitter Jitter
+
+	// rng is the random source; nil uses the global source
+	rng *rand.Rand
+
+	// mu serializes access to rng, which is not safe for concurrent use
+	mu sync.Mutex
+}
+
+// NewExponentialBackoff creates a policy using the global random source.
+func NewExponentialBackoff(base, cap time.Duration, jitter Jitter) *ExponentialBackoff {
+	return &ExponentialBackoff{
+		Base:   base,
+		Cap:    cap,
+		Jitter: jitter,
+	}
+}
+
+// NewRetryPolicy creates exponential backoff for deltas acknowledged within
+// about timeout, up to max. With equal jitter the base is twice timeout, so
+// the first retry falls in [timeout, 2*timeout) and never before an ack
+// could have arrived; other jitters start from timeout.
+func NewRetryPolicy(timeout, max time.Duration, jitter Jitter) *ExponentialBackoff {
+	base := timeout
+	if jitter == EqualJitter {
+		base = 2 * timeout
+	}
+	return NewExponentialBackoff(base, max, jitter)
+}
+
+// WithSeed makes the policy's jitter reproducible. Returns the policy.
+func (b *ExponentialBackoff) WithSeed(seed uint64) *ExponentialBackoff {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.rng = rand.New(rand.NewPCG(seed, seed))
+	return b
+}
+
+// Backoff implements RetryPolicy.
+func (b *ExponentialBackoff) Backoff(attempt int, prev time.Duration) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+
+	var d time.Duration
+	switch b.Jitter {
+	case DecorrelatedJitter:
+		if prev < b.Base {
+			prev = b.Base
+		}
+		upper := time.Duration(math.MaxInt64)
+		if prev < upper/3 {
+			upper = 3 * prev
+		}
+		d = b.Base + b.randN(upper-b.Base)
+	case FullJitter:
+		d = b.randN(b.exp(attempt))
+	case EqualJitter:
+		e := b.exp(attempt)
+		d = e - e/2 + b.randN(e/2)
+	default:
+		d = b.exp(attempt)
+	}
+
+	if b.Cap > 0 && d > b.Cap {
+		d = b.Cap
+	}
+	return d
+}
+
+// exp returns Base * 2^(attempt-1), saturating at Cap, or at the largest
+// Duration when there is no Cap, to avoid overflow.
+func (b *ExponentialBackoff) exp(attempt int) time.Duration {
+	d := b.Base
+	for i := 1; i < attempt; i++ {
+		if b.Cap > 0 && d >= b.Cap {
+			return b.Cap
+		}
+		if d > math.MaxInt64/2 {
+			return math.MaxInt64
+		}
+		d *= 2
+	}
+	return d
+}
+
+// randN returns a random duration in [0, n), or 0 if n is not positive.
+func (b *ExponentialBackoff) randN(n time.Duration) time.Duration {
+	if n <= 0 {
+		return 0
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.rng == nil {
+		return rand.N(n)
+	}
+	return time.Duration(b.rng.Int64N(int64(n)))
+}
+
+// retry returns the server's retry policy: Retry if set, otherwise
+// equal-jitter exponential backoff from RetryTimeout up to 1s, built the
+// first time it is needed so a RetryTimeout set after NewServer applies.
+func (s *Server) retry() RetryPolicy {
+	if s.Retry != nil {
+		return s.Retry
+	}
+	s.retryOnce.Do(func() {
+		s.defaultRetry = NewRetryPolicy(s.RetryTimeout, time.Second, EqualJitter)
+	})
+	return s.defaultRetry
+}
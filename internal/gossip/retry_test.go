@@ -0,0 +1,165 @@
+package gossip
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"maelstrom-broadcast/internal/queue"
+	"maelstrom-broadcast/internal/sim"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeClock is a manually advanced Clock for deterministic retry tests.
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+// fixedPolicy always backs off by the same delay.
+type fixedPolicy time.Duration
+
+func (p fixedPolicy) Backoff(int, time.Duration) time.Duration {
+	return time.Duration(p)
+}
+
+func TestExponentialBackoff_NoJitterDoublesToCap(t *testing.T) {
+	b := NewExponentialBackoff(100*time.Millisecond, time.Second, NoJitter)
+
+	assert.Equal(t, 100*time.Millisecond, b.Backoff(1, 0))
+	assert.Equal(t, 200*time.Millisecond, b.Backoff(2, 0))
+	assert.Equal(t, 400*time.Millisecond, b.Backoff(3, 0))
+	assert.Equal(t, 800*time.Millisecond, b.Backoff(4, 0))
+	assert.Equal(t, time.Second, b.Backoff(5, 0))
+	assert.Equal(t, time.Second, b.Backoff(500, 0))
+}
+
+func TestExponentialBackoff_FullJitterBounds(t *testing.T) {
+	b := NewExponentialBackoff(100*time.Millisecond, time.Second, FullJitter).WithSeed(1)
+
+	for attempt := 1; attempt < 10; attempt++ {
+		upper := b.exp(attempt)
+		for i := 0; i < 100; i++ {
+			d := b.Backoff(attempt, 0)
+			assert.GreaterOrEqual(t, d, time.Duration(0))
+			assert.Less(t, d, upper)
+			assert.LessOrEqual(t, d, time.Second)
+		}
+	}
+}
+
+func TestExponentialBackoff_EqualJitterBounds(t *testing.T) {
+	b := NewRetryPolicy(100*time.Millisecond, time.Second, EqualJitter).WithSeed(3)
+
+	for i := 0; i < 100; i++ {
+		d := b.Backoff(1, 0)
+		assert.GreaterOrEqual(t, d, 100*time.Millisecond, "retry before an ack could arrive")
+		assert.Less(t, d, 200*time.Millisecond)
+	}
+	for attempt := 2; attempt < 10; attempt++ {
+		e := b.exp(attempt)
+		d := b.Backoff(attempt, 0)
+		assert.GreaterOrEqual(t, d, min(e/2, b.Cap))
+		assert.LessOrEqual(t, d, b.Cap)
+	}
+}
+
+func TestExponentialBackoff_UncappedSaturates(t *testing.T) {
+	b := NewExponentialBackoff(time.Second, 0, NoJitter)
+	assert.Equal(t, time.Duration(math.MaxInt64), b.Backoff(100, 0))
+
+	d := NewExponentialBackoff(time.Second, 0, DecorrelatedJitter).WithSeed(4)
+	assert.Positive(t, d.Backoff(2, time.Duration(math.MaxInt64)))
+}
+
+func TestExponentialBackoff_DecorrelatedJitterBounds(t *testing.T) {
+	b := NewExponentialBackoff(100*time.Millisecond, time.Second, DecorrelatedJitter).WithSeed(2)
+
+	prev := time.Duration(0)
+	for attempt := 1; attempt < 50; attempt++ {
+		d := b.Backoff(attempt, prev)
+		lower := b.Base
+		upper := 3 * max(prev, b.Base)
+		assert.GreaterOrEqual(t, d, lower)
+		assert.LessOrEqual(t, d, min(upper, b.Cap))
+		prev = d
+	}
+}
+
+func TestExponentialBackoff_SeedIsReproducible(t *testing.T) {
+	a := NewExponentialBackoff(time.Millisecond, time.Second, FullJitter).WithSeed(7)
+	b := NewExponentialBackoff(time.Millisecond, time.Second, FullJitter).WithSeed(7)
+
+	for attempt := 1; attempt < 20; attempt++ {
+		assert.Equal(t, a.Backoff(attempt, 0), b.Backoff(attempt, 0))
+	}
+}
+
+func TestRetrySchedule_BacksOffUntilAck(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	policy := NewExponentialBackoff(100*time.Millisecond, time.Second, NoJitter)
+	pq := queue.NewPeerQueue()
+
+	// A fresh peer may be sent to straight away
+	assert.True(t, pq.RetryDue(clock.Now()))
+	pq.MarkSent(clock.Now(), policy.Backoff)
+
+	// First retry waits the base delay
+	clock.Advance(99 * time.Millisecond)
+	assert.False(t, pq.RetryDue(clock.Now()))
+	clock.Advance(time.Millisecond)
+	assert.True(t, pq.RetryDue(clock.Now()))
+	pq.MarkSent(clock.Now(), policy.Backoff)
+
+	// Second retry waits twice as long
+	clock.Advance(100 * time.Millisecond)
+	assert.False(t, pq.RetryDue(clock.Now()))
+	clock.Advance(100 * time.Millisecond)
+	assert.True(t, pq.RetryDue(clock.Now()))
+	assert.Equal(t, 2, pq.Attempts)
+
+	// An ack clears the schedule
+	pq.ResetRetry()
+	assert.True(t, pq.RetryDue(clock.Now()))
+	assert.Equal(t, 0, pq.Attempts)
+}
+
+func TestRetrySchedule_SwappablePolicy(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	pq := queue.NewPeerQueue()
+
+	for i := 0; i < 5; i++ {
+		pq.MarkSent(clock.Now(), fixedPolicy(30*time.Millisecond).Backoff)
+		assert.False(t, pq.RetryDue(clock.Now()))
+		clock.Advance(30 * time.Millisecond)
+		assert.True(t, pq.RetryDue(clock.Now()))
+	}
+	assert.Equal(t, 5, pq.Attempts)
+}
+
+func TestServer_DefaultRetryStartsAtRetryTimeout(t *testing.T) {
+	s := NewServer(sim.NewNetwork(sim.Config{}, []string{"n0"}).Node("n0"))
+	s.RetryTimeout = 40 * time.Millisecond
+	assert.Equal(t, NewRetryPolicy(40*time.Millisecond, time.Second, EqualJitter), s.retry())
+
+	s.Retry = fixedPolicy(time.Millisecond)
+	assert.Equal(t, fixedPolicy(time.Millisecond), s.retry())
+}
+
+func TestParseJitter(t *testing.T) {
+	for name, want := range map[string]Jitter{"none": NoJitter, "full": FullJitter, "equal": EqualJitter, "decorrelated": DecorrelatedJitter} {
+		got, err := ParseJitter(name)
+		assert.NoError(t, err)
+		assert.Equal(t, want, got)
+	}
+	_, err := ParseJitter("some")
+	assert.Error(t, err)
+}
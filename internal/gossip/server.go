@@ -1,90 +1,496 @@
 package gossip
 
 import (
-	// --- Standard Lib
+	// --- Standard Lib ---
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"math/rand/v2"
+	"os"
+	"slices"
 	"sync"
-	"sync/atomic"
 	"time"
 
 	// --- Internal Lib ---
+	"maelstrom-broadcast/internal/antientropy"
+	"maelstrom-broadcast/internal/health"
+	"maelstrom-broadcast/internal/idgen"
+	"maelstrom-broadcast/internal/kv"
+	"maelstrom-broadcast/internal/logging"
+	"maelstrom-broadcast/internal/logstore"
+	"maelstrom-broadcast/internal/plumtree"
 	"maelstrom-broadcast/internal/protocol"
 	"maelstrom-broadcast/internal/queue"
-
-	// --- Third Party ---
-	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+	"maelstrom-broadcast/internal/rumor"
+	"maelstrom-broadcast/internal/topology"
+	"maelstrom-broadcast/internal/txn"
+	"maelstrom-broadcast/internal/versions"
+	"maelstrom-broadcast/internal/wal"
 )
 
 // Server wraps a Maelstrom node with distributed gossip functionality.
 // It manages message storage, peer queues, and periodic gossip dissemination
 // for implementing distributed broadcast and consensus protocols.
 type Server struct {
-	// Node is the underlying Maelstrom node for network communication
-	Node *maelstrom.Node
-	
+	// Node is the network transport, normally a Maelstrom node
+	Node Transport
+
 	// Messages stores all seen messages across the distributed system
 	Messages *queue.Messages
-	
-	// Pending maps peer node IDs to their respective message queues
-	// for gossip dissemination and retry logic
-	Pending map[string]*queue.Peer
-	
-	// Counter provides atomic unique ID generation combined with node ID
-	Counter atomic.Uint64
-	
+
+	// Versions tags broadcast values by origin, so each delta carries only
+	// the entries a peer's version vector says it lacks
+	Versions *versions.Log
+
+	// Counters stores per-node contributions for the g-counter workload
+	Counters *queue.GCounter
+
+	// Txns stores transactional registers for the txn-rw-register workload
+	Txns *txn.Store
+
+	// Logs stores the keyed logs this node owns in the Kafka-style workload
+	Logs *logstore.Log
+
+	// SeqKV, LinKV and LWWKV are clients for Maelstrom's built-in
+	// key/value services, shared by all handlers
+	SeqKV *kv.Client
+	LinKV *kv.Client
+	LWWKV *kv.Client
+
+	// peerActors maps neighbour node IDs to the actors that own their
+	// queues, in-flight batches and retry timers. Only nodes chosen by the
+	// Topology strategy appear here; guarded by peersMU since a topology
+	// message may replace it while the gossip loop is running
+	peerActors map[string]*peerActor
+
+	// peersMU protects the peerActors map itself; each actor's state is
+	// only ever touched by its own goroutine. peersClosed is set by Stop,
+	// after which no new actors are started
+	peersMU     sync.RWMutex
+	peersClosed bool
+
+	// runMU guards the lifecycle: started and stopped record calls to Start
+	// and Stop, cancel ends the gossip loop, loopDone is closed when it
+	// exits and loopErr holds the error it stopped with
+	runMU    sync.Mutex
+	started  bool
+	stopped  bool
+	cancel   context.CancelFunc
+	loopDone chan struct{}
+	loopErr  error
+
+	// Topology selects which nodes this node gossips with directly
+	Topology topology.Strategy
+
+	// Mode selects how broadcast values spread over the overlay: per-peer
+	// delta queues, push-pull rumor mongering or Plumtree
+	Mode Mode
+
+	// Fanout is how many random live neighbours a push-pull round contacts
+	Fanout int
+
+	// RumorLimit is how many peers must report already knowing a value
+	// before push-pull stops spreading it
+	RumorLimit int
+
+	// PullInterval is how often push-pull pulls from one random neighbour
+	// with an empty push; zero disables it
+	PullInterval time.Duration
+
+	// rumors holds the values push-pull is still spreading; fanoutRNG picks
+	// each round's targets and pullAt schedules the next pull, both guarded
+	// by fanoutMU
+	rumors    *rumor.Mill
+	fanoutRNG *rand.Rand
+	pullAt    time.Time
+	fanoutMU  sync.Mutex
+
+	// GraftTimeout is how long Plumtree waits for an announced value to
+	// arrive through the tree before grafting the link it was announced on
+	GraftTimeout time.Duration
+
+	// IHaveInterval controls how often Plumtree announces new values to
+	// lazy neighbours; announcements batch up in between
+	IHaveInterval time.Duration
+
+	// ihaveAt schedules the next announcement round; guarded by ihaveMU
+	ihaveAt time.Time
+	ihaveMU sync.Mutex
+
+	// tree holds the Plumtree eager/lazy split of the neighbours
+	tree *plumtree.State
+
+	// Health tracks neighbour liveness so gossip skips dead peers
+	Health *health.Detector
+
+	// Metrics counts traffic for the stats message
+	Metrics *Metrics
+
+	// Logger is the root structured logger; per-component loggers tagged
+	// with the node ID are derived from it on first use
+	Logger *slog.Logger
+
+	// logOnce and loggers hold the derived component loggers
+	logOnce sync.Once
+	loggers *loggers
+
+	// IDScheme selects how generate builds unique IDs: the original
+	// "<node>_<n>" counter or time-ordered Snowflake integers
+	IDScheme idgen.Scheme
+
+	// IDs issues unique IDs for generate; built by init from IDScheme
+	// once the cluster membership is known
+	IDs idgen.Generator
+
+	// WAL, when enabled with WithWAL, durably records broadcast values and
+	// ID reservations so a restarted node recovers them
+	WAL *wal.Log
+
+	// CompactEvery is how many WAL records may build up before the log is
+	// compacted into a snapshot
+	CompactEvery int
+
+	// IDBlock is how many counter IDs are reserved per WAL record
+	IDBlock uint64
+
+	// walDir, walOnce and walErr track opening the WAL; blockIDs is the
+	// WAL-backed counter whose reservations snapshots must cover
+	walDir   string
+	walOnce  sync.Once
+	walErr   error
+	blockIDs *idgen.BlockCounter
+
 	// initOnce ensures topology initialization happens only once
 	initOnce sync.Once
-	
+
+	// Workload picks the shape of read replies; AutoWorkload infers it
+	// from the requests received
+	Workload Workload
+
+	// inferred is the workload of the first request that identified one;
+	// guarded by workloadMU
+	inferred   Workload
+	workloadMU sync.Mutex
+
 	// GossipInterval controls how frequently gossip messages are sent
 	GossipInterval time.Duration
-	
-	// RetryTimeout defines how long to wait before retrying failed messages
+
+	// RetryTimeout defines how long to wait before retrying failed messages;
+	// it is the base of the default Retry policy
 	RetryTimeout time.Duration
-	
+
+	// Retry decides how long to back off between retransmissions of an
+	// unacknowledged delta. When nil, equal-jitter exponential backoff
+	// whose first retry comes after RetryTimeout, up to 1s, is built on
+	// first use; see retry
+	Retry RetryPolicy
+
+	// retryOnce and defaultRetry hold the policy built when Retry is nil
+	retryOnce    sync.Once
+	defaultRetry RetryPolicy
+
+	// Clock supplies the current time for retry scheduling
+	Clock Clock
+
 	// GossipMax limits the number of messages sent in each gossip batch
 	GossipMax int
+
+	// AntiEntropyInterval controls how often a digest is exchanged with one
+	// neighbour to repair gaps the delta queues missed; zero disables it
+	AntiEntropyInterval time.Duration
+
+	// DigestWidth is the number of consecutive values each digest bucket covers
+	DigestWidth int
+
+	// syncAt and syncNext schedule digest exchanges and pick the next
+	// neighbour in turn; guarded by syncMU
+	syncAt   time.Time
+	syncNext int
+	syncMU   sync.Mutex
+
+	// ForwardTimeout bounds requests forwarded to another node, such as
+	// log appends routed to a key's owner
+	ForwardTimeout time.Duration
 }
 
-// NewServer creates a new gossip server wrapping the provided Maelstrom node.
+// NewServer creates a new gossip server wrapping the provided transport.
 // Initializes default timing parameters: 50ms gossip interval, 100ms retry timeout,
-// and maximum batch size of 128 messages per gossip round.
-func NewServer(n *maelstrom.Node) *Server {
-	return &Server{
-		Node:           n,
-		Messages:       queue.NewMessagesQueue(),
-		GossipInterval: 50 * time.Millisecond,
-		RetryTimeout:   100 * time.Millisecond,
-		GossipMax:      128,
+// maximum batch size of 128 messages per gossip round, and 1s forwarding timeout.
+// Gossips over a full mesh until a different Topology strategy is set, and
+// backs off retransmissions exponentially with full jitter up to 1s.
+// Values spread through deltas carrying the origin-tagged entries each peer's
+// version vector lacks unless Mode selects push-pull,
+// which contacts 3 random peers a round and retires a rumor once 2 peers
+// already knew it, or Plumtree, which announces values over lazy links every
+// 500ms and grafts a link whose announced value is 500ms overdue.
+// Broadcast values are kept as runs of consecutive integers, since Maelstrom
+// hands them out in sequence, and a digest of them is exchanged with one
+// neighbour per second to repair any gaps. All traffic through n is counted
+// in Metrics. With WithWAL, state logged by a previous run is replayed here
+// if n already knows its node ID, or at init otherwise.
+func NewServer(n Transport, opts ...Option) *Server {
+	m := NewMetrics()
+	n = &meteredTransport{Transport: n, metrics: m}
+	s := &Server{
+		Node:                n,
+		Metrics:             m,
+		Logger:              logging.New(os.Stderr, logging.DefaultConfig()),
+		Messages:            queue.NewMessagesQueue(queue.IntervalSet),
+		Versions:            versions.NewLog(),
+		Counters:            queue.NewGCounter(),
+		Logs:                logstore.New(),
+		Txns:                txn.NewStore(n.ID()),
+		SeqKV:               kv.New(n, kv.SeqKV),
+		LinKV:               kv.New(n, kv.LinKV),
+		LWWKV:               kv.New(n, kv.LWWKV),
+		GossipInterval:      50 * time.Millisecond,
+		RetryTimeout:        100 * time.Millisecond,
+		GossipMax:           128,
+		AntiEntropyInterval: time.Second,
+		DigestWidth:         antientropy.DefaultBucketWidth,
+		ForwardTimeout:      time.Second,
+		IDScheme:            idgen.CounterScheme,
+		CompactEvery:        10000,
+		IDBlock:             1000,
+		Topology:            topology.Mesh{},
+		Mode:                DeltaMode,
+		Fanout:              3,
+		RumorLimit:          2,
+		PullInterval:        time.Second,
+		rumors:              rumor.NewMill(),
+		GraftTimeout:        500 * time.Millisecond,
+		IHaveInterval:       500 * time.Millisecond,
+		tree:                plumtree.New(),
+		fanoutRNG:           rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())),
+		Clock:               realClock{},
+		Health:              health.NewDetector(health.DefaultConfig()),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.walDir != "" && n.ID() != "" {
+		if err := s.restore(); err != nil {
+			// logs() is not used yet: main may still replace Logger
+			s.Logger.With(logging.ComponentKey, "wal").Error("restore failed", "dir", s.walDir, "err", err)
+		}
+	}
+	return s
+}
+
+// initPeers sets up peer actors for this node's overlay neighbours. Safe to
+// call from both init and topology handlers; only the first call has any
+// effect. Gossip rounds only run once Start is called.
+func (s *Server) initPeers() {
+	s.initOnce.Do(func() {
+		s.setNeighbors(nil)
+	})
+}
+
+// setNeighbors recomputes the overlay from the node list and Maelstrom's
+// suggested topology, then reconciles the peer actors against it. Existing
+// neighbours keep their actors and in-flight state; new neighbours get a
+// fresh actor, which learns the peer's version vector before sending it
+// anything, so a late joiner is only sent what it lacks; actors for nodes
+// that are no longer neighbours are stopped.
+func (s *Server) setNeighbors(given protocol.Topology) {
+	neighbors := topology.Build(s.Topology, s.Node.ID(), s.Node.NodeIDs(), given)
+
+	s.peersMU.Lock()
+	defer s.peersMU.Unlock()
+
+	if s.peersClosed {
+		return
+	}
+	next := make(map[string]*peerActor, len(neighbors))
+	for _, id := range neighbors {
+		if a, ok := s.peerActors[id]; ok {
+			next[id] = a
+			delete(s.peerActors, id)
+			continue
+		}
+		next[id] = newPeerActor(s, id)
+	}
+	for _, a := range s.peerActors {
+		a.stop()
+	}
+	s.peerActors = next
+	s.tree.SetPeers(neighbors)
+	s.Health.SetPeers(neighbors, s.Clock.Now())
+	s.logs().gossip.Info("neighbours set", "strategy", fmt.Sprintf("%T", s.Topology), "peers", neighbors)
+}
+
+// peers returns a snapshot of the current neighbour actors that is safe to
+// iterate while the topology changes underneath.
+func (s *Server) peers() map[string]*peerActor {
+	s.peersMU.RLock()
+	defer s.peersMU.RUnlock()
+
+	out := make(map[string]*peerActor, len(s.peerActors))
+	for id, a := range s.peerActors {
+		out[id] = a
+	}
+	return out
+}
+
+// peer returns the actor for a single neighbour, if it is one.
+func (s *Server) peer(id string) (*peerActor, bool) {
+	s.peersMU.RLock()
+	defer s.peersMU.RUnlock()
+
+	a, ok := s.peerActors[id]
+	return a, ok
+}
+
+// addBroadcast records a value a client broadcast to this node. If it is
+// new it is tagged as the next entry from this node, which every peer
+// actor then sends on. Reports whether it was new, or why it could not be
+// recorded.
+func (s *Server) addBroadcast(v int, src string) (bool, error) {
+	if fresh, err := s.record(v, src); !fresh {
+		return false, err
+	}
+	if s.Mode != PushPullMode {
+		s.Versions.Append(v)
+	}
+	return true, nil
+}
+
+// addEntry records a tagged entry received from src. The tag is kept even
+// for a value already known, so this node's version vector stays gap-free,
+// but not for a value that could not be recorded, so the peer resends it.
+// Reports whether the value was new.
+func (s *Server) addEntry(e protocol.Entry, src string) (bool, error) {
+	fresh, err := s.record(e.Value, src)
+	if err != nil {
+		return false, err
+	}
+	s.Versions.Add(e)
+	return fresh, nil
+}
+
+// addMessage records a value learned from src without a tag, such as a
+// digest repair, and if it is new queues it for every neighbour except src;
+// in Plumtree mode only eager neighbours get it queued. Reports whether it
+// was new.
+func (s *Server) addMessage(v int, src string) (bool, error) {
+	if fresh, err := s.record(v, src); !fresh {
+		return false, err
+	}
+	if s.Mode == PushPullMode {
+		return true, nil
+	}
+	for peer, a := range s.peers() {
+		if peer != s.Node.ID() && peer != src && s.pushesTo(peer) {
+			a.cast(enqueueMsg{values: []int{v}})
+		}
+	}
+	return true, nil
+}
+
+// record adds a broadcast value learned from src to the message set and,
+// if it is new, to the dissemination state: in push-pull mode it becomes a
+// hot rumor, in Plumtree mode it is announced to lazy neighbours. Reports
+// whether it was new; with a WAL, an error means it could not be persisted
+// and was not kept.
+func (s *Server) record(v int, src string) (bool, error) {
+	fresh, err := s.Messages.Insert(v)
+	if !fresh {
+		return false, err
+	}
+	s.Metrics.Seen(v, s.Clock.Now())
+	switch s.Mode {
+	case PushPullMode:
+		s.rumors.Add(v)
+	case PlumtreeMode:
+		s.tree.Received(v, src)
+	}
+	return true, nil
+}
+
+// pushesTo reports whether new values are pushed to peer in deltas: always
+// in delta mode, only over eager links in Plumtree mode and never in
+// push-pull mode.
+func (s *Server) pushesTo(peer string) bool {
+	switch s.Mode {
+	case DeltaMode:
+		return true
+	case PlumtreeMode:
+		return s.tree.Eager(peer)
+	}
+	return false
+}
+
+// resend arranges for values a peer turned out to be missing to reach it:
+// they are queued on its actor, or in push-pull mode made hot rumors again.
+func (s *Server) resend(peer string, values []int) {
+	if s.Mode == PushPullMode {
+		s.rumors.Add(values...)
+		return
+	}
+	if a, ok := s.peer(peer); ok {
+		a.cast(enqueueMsg{values: values})
+	}
+}
+
+// Tick runs a single gossip round.
+// Asks each neighbour's actor to send its next delta, so new messages
+// propagate throughout the distributed system. Each actor manages its own
+// in-flight batch and retry logic: a peer whose last delta is still
+// unacknowledged is skipped until its backoff deadline passes, and peers the
+// failure detector considers dead are skipped until they answer a probe.
+// In push-pull mode broadcast values travel in pushes to a few random peers
+// instead of in deltas; in Plumtree mode lazy neighbours are sent
+// announcements and missing values are grafted. Periodically starts a
+// digest exchange with one neighbour, and trims the version log and the
+// transaction replication log. Peers are
+// visited in ID order, each finishing before the next starts, so a seeded
+// simulation replays identically. Returns any errors sending.
+func (s *Server) Tick() error {
+	now := s.Clock.Now()
+	errs := []error{s.sendProbes(now)}
+
+	peers := s.peers()
+	ids := slices.Sorted(maps.Keys(peers))
+	for _, peerID := range ids {
+		if err := peers[peerID].call(tickMsg{now: now}); !errors.Is(err, errPeerStopped) {
+			errs = append(errs, err)
+		}
+	}
+
+	switch s.Mode {
+	case PushPullMode:
+		errs = append(errs, s.pushRound(now, ids))
+	case PlumtreeMode:
+		errs = append(errs, s.lazyRound(now))
 	}
+	errs = append(errs, s.sendDigest(now, ids))
+	s.trimVersions(peers, ids)
+	s.trimTxns(peers)
+	return errors.Join(errs...)
 }
 
-// HandlePeerQueues runs the background gossip dissemination loop.
-// Periodically drains peer queues and sends delta messages to propagate
-// new messages throughout the distributed system. Manages in-flight messages
-// and retry logic for failed transmissions.
-func (s *Server) HandlePeerQueues() error {
-	ticker := time.NewTicker(s.GossipInterval)
-
-	for range ticker.C {
-		for peerID, pq := range s.Pending {
-			
-			if pq.InFlight == nil {
-				batch := pq.DrainBatch(s.GossipMax)
-				pq.MU.Lock()
-				pq.InFlight = batch
-				pq.MU.Unlock()
-			} 
-
-			if len(pq.InFlight) == 0 {
-				continue
-			}
-
-			pq.MU.Lock()
-			s.Node.Send(peerID, protocol.DeltaReq{
-				Type:     "delta",
-				Messages: pq.InFlight,
-			})
-			pq.MU.Unlock()
+// trimVersions drops the version log entries every neighbour has
+// acknowledged, lazy and unreachable ones included, so a neighbour coming
+// back from a prune or a partition still finds what it lacks in the log
+// and is sent only that. Nothing is trimmed while one of them has yet to
+// report its vector. Only a neighbour that lacks entries trimmed before it
+// became one is sent the values untagged instead; see peerActor.entries.
+func (s *Server) trimVersions(peers map[string]*peerActor, ids []string) {
+	var floor versions.Vector
+	for _, id := range ids {
+		var have versions.Vector
+		if err := peers[id].call(vectorMsg{out: &have}); err != nil || have == nil {
+			return
+		}
+		if floor == nil {
+			floor = have
+		} else {
+			floor.Meet(have)
 		}
 	}
-	return nil
+	if n := s.Versions.Trim(floor); n > 0 {
+		s.logs().gossip.Debug("trimmed version log", "entries", n)
+	}
 }
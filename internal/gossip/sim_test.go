@@ -0,0 +1,824 @@
+package gossip
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"maelstrom-broadcast/internal/idgen"
+	"maelstrom-broadcast/internal/kv"
+	"maelstrom-broadcast/internal/protocol"
+	"maelstrom-broadcast/internal/sim"
+	"maelstrom-broadcast/internal/topology"
+	"maelstrom-broadcast/internal/versions"
+	"maelstrom-broadcast/internal/wal"
+
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// cluster is a set of Servers wired to a simulated network.
+type cluster struct {
+	net     *sim.Network
+	ids     []string
+	servers map[string]*Server
+	msgID   int
+}
+
+// newCluster starts size servers on a seeded simulated network, each
+// ticking on the virtual clock, and sends them the given topology strategy.
+// Each configure func is applied to every server before it is registered.
+// The servers are stopped when the test ends.
+func newCluster(t *testing.T, cfg sim.Config, size int, strategy topology.Strategy, configure ...func(*Server)) *cluster {
+	t.Helper()
+
+	ids := make([]string, size)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("n%d", i)
+	}
+	net := sim.NewNetwork(cfg, ids)
+	for _, svc := range []string{kv.SeqKV, kv.LinKV, kv.LWWKV} {
+		net.AddService(svc, kv.NewFake())
+	}
+
+	c := &cluster{net: net, ids: ids, servers: make(map[string]*Server)}
+	for i, id := range ids {
+		node := net.Node(id)
+		s := NewServer(node)
+		s.Clock = net
+		s.Topology = strategy
+		s.Retry = NewRetryPolicy(s.RetryTimeout, time.Second, EqualJitter).WithSeed(cfg.Seed + uint64(i))
+		s.Health.WithSeed(cfg.Seed + uint64(i))
+		s.SeedFanout(cfg.Seed + uint64(i))
+		for _, fn := range configure {
+			fn(s)
+		}
+		s.Register(node)
+		net.Every(s.GossipInterval, func() { assert.NoError(t, s.Tick()) })
+		t.Cleanup(func() { assert.NoError(t, s.Stop()) })
+		c.servers[id] = s
+	}
+	net.Init()
+	return c
+}
+
+// topology sends every node Maelstrom's topology message.
+func (c *cluster) topology() {
+	for _, id := range c.ids {
+		c.inject(id, protocol.TopologyReq{Type: "topology"})
+	}
+	c.net.RunFor(time.Second)
+}
+
+// inject sends a client request to node id.
+func (c *cluster) inject(id string, body any) {
+	c.msgID++
+	b, _ := json.Marshal(body)
+	m := make(map[string]any)
+	json.Unmarshal(b, &m)
+	m["msg_id"] = c.msgID
+	c.net.Inject("c1", id, m)
+}
+
+// converged reports whether every node has seen exactly want.
+func (c *cluster) converged(want []int) bool {
+	for _, s := range c.servers {
+		got := s.Messages.Snapshot()
+		slices.Sort(got)
+		if !slices.Equal(got, want) {
+			return false
+		}
+	}
+	return true
+}
+
+// broadcast injects values 0..count-1 round-robin across nodes, spaced by gap.
+func (c *cluster) broadcast(count int, gap time.Duration) []int {
+	var want []int
+	for i := 0; i < count; i++ {
+		c.inject(c.ids[i%len(c.ids)], protocol.BroadcastReq{Type: "broadcast", Message: i})
+		want = append(want, i)
+		c.net.RunFor(gap)
+	}
+	return want
+}
+
+// request builds a client message to node id, for calling handlers directly.
+func (c *cluster) request(id string, body any) maelstrom.Message {
+	c.msgID++
+	b, _ := json.Marshal(body)
+	m := make(map[string]any)
+	json.Unmarshal(b, &m)
+	m["msg_id"] = c.msgID
+	buf, _ := json.Marshal(m)
+	return maelstrom.Message{Src: "c1", Dest: id, Body: buf}
+}
+
+func TestSim_Broadcast25NodesConverges(t *testing.T) {
+	for _, strategy := range []topology.Strategy{topology.Mesh{}, topology.Tree{K: 4}, topology.Grid{}} {
+		t.Run(fmt.Sprintf("%T", strategy), func(t *testing.T) {
+			c := newCluster(t, sim.Config{Seed: 1, Latency: 100 * time.Millisecond}, 25, strategy)
+			c.topology()
+
+			want := c.broadcast(50, 10*time.Millisecond)
+			ok := c.net.RunUntil(func() bool { return c.converged(want) }, 10*time.Second)
+			assert.True(t, ok, "cluster did not converge")
+		})
+	}
+}
+
+func TestSim_ModesConverge(t *testing.T) {
+	for _, mode := range []Mode{DeltaMode, PushPullMode, PlumtreeMode} {
+		t.Run(string(mode), func(t *testing.T) {
+			cfg := sim.Config{Seed: 59, Latency: 100 * time.Millisecond, DropRate: 0.05}
+			c := newCluster(t, cfg, 25, topology.Mesh{}, func(s *Server) { s.Mode = mode })
+			c.topology()
+			before := c.net.Stats().Sent
+
+			want := c.broadcast(100, 10*time.Millisecond)
+			ok := c.net.RunUntil(func() bool { return c.converged(want) }, 20*time.Second)
+			require.True(t, ok, "cluster did not converge")
+
+			// Let rumors die down, then count what dissemination cost
+			c.net.RunFor(5 * time.Second)
+			t.Logf("%s: %.1f msgs/op", mode, float64(c.net.Stats().Sent-before)/float64(len(want)))
+
+			if mode == PushPullMode {
+				for id, s := range c.servers {
+					assert.Zero(t, s.rumors.Len(), "%s still has hot rumors", id)
+				}
+			}
+		})
+	}
+}
+
+func TestSim_PushPullPullsFromSilentPeer(t *testing.T) {
+	c := newCluster(t, sim.Config{Seed: 71, Latency: 10 * time.Millisecond}, 2, topology.Mesh{}, func(s *Server) {
+		s.Mode = PushPullMode
+		s.AntiEntropyInterval = 0
+	})
+	c.topology()
+
+	// n1 never pushes, so n0 only learns the value by pulling it
+	c.servers["n1"].Fanout = 0
+	c.inject("n1", protocol.BroadcastReq{Type: "broadcast", Message: 7})
+	require.True(t, c.net.RunUntil(func() bool { return c.converged([]int{7}) }, 5*time.Second))
+
+	// Pulls of a value n0 already holds are fed back, so n1's rumor retires
+	assert.True(t, c.net.RunUntil(func() bool { return c.servers["n1"].rumors.Len() == 0 }, 5*time.Second))
+}
+
+func TestSim_PushPullIdlesWithoutHotRumors(t *testing.T) {
+	c := newCluster(t, sim.Config{Seed: 73, Latency: 10 * time.Millisecond}, 5, topology.Mesh{}, func(s *Server) {
+		s.Mode = PushPullMode
+		s.AntiEntropyInterval = 0
+	})
+	c.topology()
+
+	want := c.broadcast(20, 10*time.Millisecond)
+	require.True(t, c.net.RunUntil(func() bool { return c.converged(want) }, 10*time.Second))
+	require.True(t, c.net.RunUntil(func() bool {
+		for _, s := range c.servers {
+			if s.rumors.Len() > 0 {
+				return false
+			}
+		}
+		return true
+	}, 10*time.Second))
+
+	// Only the periodic pulls and their replies remain: one of each per
+	// node per PullInterval
+	sent := c.net.Stats().Sent
+	c.net.RunFor(5 * time.Second)
+	assert.LessOrEqual(t, c.net.Stats().Sent-sent, 2*len(c.ids)*6)
+}
+
+func TestSim_NewNeighboursOnlyGetMissingValues(t *testing.T) {
+	c := newCluster(t, sim.Config{Seed: 67, Latency: 10 * time.Millisecond}, 5, topology.Ring{})
+	c.topology()
+	want := c.broadcast(200, time.Millisecond)
+	require.True(t, c.net.RunUntil(func() bool { return c.converged(want) }, 10*time.Second))
+
+	sent := func() uint64 {
+		var n uint64
+		for _, s := range c.servers {
+			n += s.Metrics.Snapshot().Batches.Messages
+		}
+		return n
+	}
+	c.net.RunFor(time.Second)
+	before := sent()
+
+	// Every node gains two neighbours that already hold every value
+	for _, s := range c.servers {
+		s.Topology = topology.Mesh{}
+	}
+	c.topology()
+	assert.Equal(t, before, sent(), "new neighbours were sent values they already held")
+
+	c.inject("n0", protocol.BroadcastReq{Type: "broadcast", Message: 200})
+	want = append(want, 200)
+	assert.True(t, c.net.RunUntil(func() bool { return c.converged(want) }, 10*time.Second))
+}
+
+func TestSim_PlumtreeIgnoresRedeliveries(t *testing.T) {
+	c := newCluster(t, sim.Config{Seed: 73, Latency: 10 * time.Millisecond}, 1, topology.Mesh{},
+		func(s *Server) { s.Mode = PlumtreeMode })
+	c.topology()
+	n0 := c.servers["n0"]
+
+	// n1 exists only in n0's tree, so every delta from it is injected here
+	n0.tree.SetPeers([]string{"n1"})
+	deliver := func(batch uint64, messages []int, entries []protocol.Entry) {
+		require.NoError(t, c.net.Inject("n1", "n0", protocol.DeltaReq{
+			Type: "delta", BatchID: batch, Messages: messages, Entries: entries,
+		}))
+		c.net.RunFor(100 * time.Millisecond)
+	}
+
+	deliver(1, []int{5}, []protocol.Entry{{Origin: "x", Seq: 1, Value: 9}})
+	deliver(1, []int{5}, []protocol.Entry{{Origin: "x", Seq: 1, Value: 9}})
+	assert.True(t, n0.tree.Eager("n1"), "a retransmission pruned the link")
+
+	deliver(2, []int{5}, nil)
+	assert.False(t, n0.tree.Eager("n1"), "a new batch of duplicates did not prune the link")
+}
+
+func TestSim_PlumtreeRepairsAfterPartition(t *testing.T) {
+	c := newCluster(t, sim.Config{Seed: 61, Latency: 10 * time.Millisecond}, 9, topology.Grid{},
+		func(s *Server) { s.Mode = PlumtreeMode })
+	c.topology()
+
+	// Build the tree, then cut part of it off
+	want := c.broadcast(20, 10*time.Millisecond)
+	require.True(t, c.net.RunUntil(func() bool { return c.converged(want) }, 10*time.Second))
+	pruned := 0
+	for _, s := range c.servers {
+		pruned += len(s.tree.Lazy())
+	}
+	assert.Greater(t, pruned, 0, "redundant links were not pruned")
+
+	c.net.Partition([]string{"n0", "n1", "n3"}, []string{"n2", "n4", "n5", "n6", "n7", "n8"})
+	for i := 100; i < 120; i++ {
+		c.inject(c.ids[i%len(c.ids)], protocol.BroadcastReq{Type: "broadcast", Message: i})
+		want = append(want, i)
+		c.net.RunFor(10 * time.Millisecond)
+	}
+	c.net.RunFor(2 * time.Second)
+	c.net.Heal()
+
+	ok := c.net.RunUntil(func() bool { return c.converged(want) }, 10*time.Second)
+	assert.True(t, ok, "cluster did not converge after healing")
+}
+
+func TestSim_PlumtreeKeepsVectorsOfLazyPeers(t *testing.T) {
+	c := newCluster(t, sim.Config{Seed: 89, Latency: 10 * time.Millisecond}, 9, topology.Grid{},
+		func(s *Server) { s.Mode = PlumtreeMode })
+	c.topology()
+
+	want := c.broadcast(40, 10*time.Millisecond)
+	require.True(t, c.net.RunUntil(func() bool { return c.converged(want) }, 10*time.Second))
+	c.net.RunFor(2 * time.Second)
+
+	pruned := 0
+	for id, s := range c.servers {
+		for _, peer := range s.tree.Lazy() {
+			pruned++
+			a, ok := s.peer(peer)
+			require.True(t, ok)
+			var have versions.Vector
+			require.NoError(t, a.call(vectorMsg{out: &have}))
+			assert.NotNil(t, have, "%s forgot the vector of lazy peer %s", id, peer)
+		}
+	}
+	require.Greater(t, pruned, 0, "redundant links were not pruned")
+
+	// Lazy peers count towards the trim floor, and their announcements keep
+	// their vectors current, so the log is still trimmed
+	ok := c.net.RunUntil(func() bool {
+		for _, s := range c.servers {
+			if len(s.Versions.Since(nil, 0)) > 0 {
+				return false
+			}
+		}
+		return true
+	}, 10*time.Second)
+	assert.True(t, ok, "version log not trimmed with lazy links")
+}
+
+func TestSim_VersionLogKeepsEntriesForPartitionedPeer(t *testing.T) {
+	c := newCluster(t, sim.Config{Seed: 79, Latency: 10 * time.Millisecond}, 3, topology.Mesh{})
+	c.topology()
+	want := c.broadcast(30, 10*time.Millisecond)
+	require.True(t, c.net.RunUntil(func() bool { return c.converged(want) }, 10*time.Second))
+	c.net.RunFor(time.Second)
+	for id, s := range c.servers {
+		assert.Empty(t, s.Versions.Since(nil, 0), "%s kept entries every neighbour holds", id)
+	}
+
+	// n2 misses values the others keep for it even once it is considered dead
+	c.net.Partition([]string{"n0", "n1"}, []string{"n2"})
+	var missed []int
+	for i := 100; i < 130; i++ {
+		c.inject(c.ids[i%2], protocol.BroadcastReq{Type: "broadcast", Message: i})
+		missed = append(missed, i)
+		c.net.RunFor(10 * time.Millisecond)
+	}
+	want = append(want, missed...)
+	c.net.RunFor(5 * time.Second)
+	require.Nil(t, c.servers["n0"].Versions.Behind(c.servers["n2"].Versions.Vector()),
+		"entries n2 lacks were trimmed")
+
+	shipped := func() uint64 {
+		var n uint64
+		for _, s := range c.servers {
+			n += s.Metrics.Snapshot().Batches.Messages
+		}
+		return n
+	}
+	before := shipped()
+	c.net.Heal()
+
+	require.True(t, c.net.RunUntil(func() bool { return c.converged(want) }, 10*time.Second))
+	c.net.RunFor(time.Second)
+	assert.LessOrEqual(t, shipped()-before, uint64(2*len(missed)),
+		"healing shipped more than each of n0 and n1 sending n2 what it missed")
+	for id, s := range c.servers {
+		assert.Empty(t, s.Versions.Since(nil, 0), "%s kept entries every neighbour holds", id)
+	}
+}
+
+func TestSim_NewNeighbourSkipsTrimmedEntries(t *testing.T) {
+	c := newCluster(t, sim.Config{Seed: 83, Latency: 10 * time.Millisecond}, 3, topology.Given{})
+	shape := func(topo protocol.Topology) {
+		for _, id := range c.ids {
+			c.inject(id, protocol.TopologyReq{Type: "topology", Topology: topo})
+		}
+		c.net.RunFor(time.Second)
+	}
+
+	// n2 joins only after n0 and n1 trimmed everything they exchanged
+	shape(protocol.Topology{"n0": {"n1"}, "n1": {"n0"}, "n2": {}})
+	for i := 0; i < 20; i++ {
+		c.inject(c.ids[i%2], protocol.BroadcastReq{Type: "broadcast", Message: i})
+		c.net.RunFor(10 * time.Millisecond)
+	}
+	c.net.RunFor(time.Second)
+	require.Empty(t, c.servers["n0"].Versions.Since(nil, 0))
+	require.NotNil(t, c.servers["n0"].Versions.Behind(c.servers["n2"].Versions.Vector()))
+
+	shape(protocol.Topology{"n0": {"n1", "n2"}, "n1": {"n0", "n2"}, "n2": {"n0", "n1"}})
+	want := make([]int, 20)
+	for i := range want {
+		want[i] = i
+	}
+	vectorsAgree := func() bool {
+		v := c.servers["n0"].Versions.Vector()
+		return maps.Equal(v, c.servers["n1"].Versions.Vector()) && maps.Equal(v, c.servers["n2"].Versions.Vector())
+	}
+	require.True(t, c.net.RunUntil(func() bool { return c.converged(want) }, 10*time.Second))
+	assert.True(t, c.net.RunUntil(vectorsAgree, 10*time.Second), "n2 never skipped the trimmed entries")
+}
+
+func TestSim_BroadcastSurvivesLossAndDuplication(t *testing.T) {
+	cfg := sim.Config{Seed: 7, Latency: 20 * time.Millisecond, Jitter: 30 * time.Millisecond, DropRate: 0.2, DupRate: 0.1}
+	c := newCluster(t, cfg, 5, topology.Ring{})
+	c.topology()
+
+	want := c.broadcast(40, 5*time.Millisecond)
+	ok := c.net.RunUntil(func() bool { return c.converged(want) }, 20*time.Second)
+	assert.True(t, ok, "cluster did not converge")
+
+	stats := c.net.Stats()
+	assert.Greater(t, stats.Dropped, 0)
+	assert.Greater(t, stats.Duplicated, 0)
+}
+
+func TestSim_BroadcastHealsAfterPartition(t *testing.T) {
+	c := newCluster(t, sim.Config{Seed: 3, Latency: 10 * time.Millisecond}, 5, topology.Mesh{})
+	c.topology()
+
+	c.net.Partition([]string{"n0", "n1"}, []string{"n2", "n3", "n4"})
+	want := c.broadcast(20, 10*time.Millisecond)
+	c.net.RunFor(5 * time.Second)
+	assert.False(t, c.converged(want), "partition should prevent convergence")
+
+	c.net.Heal()
+	ok := c.net.RunUntil(func() bool { return c.converged(want) }, 10*time.Second)
+	assert.True(t, ok, "cluster did not converge after healing")
+}
+
+func TestSim_AntiEntropyRepairsMissedValues(t *testing.T) {
+	c := newCluster(t, sim.Config{Seed: 17, Latency: 10 * time.Millisecond}, 5, topology.Ring{})
+	c.topology()
+
+	want := c.broadcast(20, 10*time.Millisecond)
+	require.True(t, c.net.RunUntil(func() bool { return c.converged(want) }, 5*time.Second))
+
+	// Values added behind the queues' back only spread through digests
+	c.servers["n2"].Messages.Add(1000)
+	c.servers["n4"].Messages.Add(1001)
+	want = append(want, 1000, 1001)
+
+	ok := c.net.RunUntil(func() bool { return c.converged(want) }, 20*time.Second)
+	assert.True(t, ok, "anti-entropy did not repair the gap")
+}
+
+func TestSim_AntiEntropyAcrossDigestWidths(t *testing.T) {
+	c := newCluster(t, sim.Config{Seed: 23, Latency: 10 * time.Millisecond}, 3, topology.Ring{})
+	c.servers["n1"].DigestWidth = 8
+	c.servers["n2"].DigestWidth = 1000
+	c.topology()
+
+	want := c.broadcast(20, 10*time.Millisecond)
+	require.True(t, c.net.RunUntil(func() bool { return c.converged(want) }, 5*time.Second))
+
+	// Each exchange is diffed with the width its requester chose
+	c.servers["n0"].Messages.Add(5000)
+	c.servers["n1"].Messages.Add(-7)
+	c.servers["n2"].Messages.Add(123456)
+	want = append(want, 5000, -7, 123456)
+	slices.Sort(want)
+
+	ok := c.net.RunUntil(func() bool { return c.converged(want) }, 20*time.Second)
+	assert.True(t, ok, "anti-entropy did not repair the gap")
+}
+
+func TestSim_IsDeterministic(t *testing.T) {
+	run := func() sim.Stats {
+		cfg := sim.Config{Seed: 11, Latency: 10 * time.Millisecond, Jitter: 10 * time.Millisecond, DropRate: 0.1}
+		c := newCluster(t, cfg, 5, topology.Ring{})
+		c.topology()
+		c.broadcast(10, 10*time.Millisecond)
+		c.net.RunFor(2 * time.Second)
+		return c.net.Stats()
+	}
+	assert.Equal(t, run(), run())
+}
+
+func TestSim_CounterConverges(t *testing.T) {
+	c := newCluster(t, sim.Config{Seed: 5, Latency: 10 * time.Millisecond}, 3, topology.Mesh{})
+
+	c.net.Partition([]string{"n0"}, []string{"n1", "n2"})
+	for i, id := range c.ids {
+		c.inject(id, protocol.AddReq{Type: "add", Delta: i + 1})
+	}
+	c.net.RunFor(time.Second)
+	c.net.Heal()
+
+	ok := c.net.RunUntil(func() bool {
+		for _, s := range c.servers {
+			if s.Counters.Value() != 6 {
+				return false
+			}
+		}
+		return true
+	}, 10*time.Second)
+	assert.True(t, ok, "counter did not converge")
+}
+
+func TestSim_ReadReplyFollowsWorkload(t *testing.T) {
+	tests := map[string]struct {
+		workload Workload
+		ops      []map[string]any
+		want     string
+	}{
+		"nothing seen":        {ops: nil, want: `"value"`},
+		"add seen":            {ops: []map[string]any{{"type": "add", "delta": 2}}, want: `"value"`},
+		"broadcast seen":      {ops: []map[string]any{{"type": "broadcast", "message": 1}}, want: `"messages"`},
+		"configured over ops": {workload: CounterWorkload, ops: []map[string]any{{"type": "broadcast", "message": 1}}, want: `"value"`},
+		"configured, none":    {workload: BroadcastWorkload, want: `"messages"`},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			c := newCluster(t, sim.Config{Seed: 71}, 1, topology.Mesh{}, func(s *Server) { s.Workload = tt.workload })
+			for _, op := range tt.ops {
+				c.inject("n0", op)
+			}
+			c.inject("n0", protocol.ReadReq{Type: "read"})
+			c.net.RunFor(time.Second)
+
+			replies := c.net.Replies("c1")
+			require.NotEmpty(t, replies)
+			assert.Contains(t, string(replies[len(replies)-1].Body), tt.want)
+		})
+	}
+}
+
+func TestSim_KafkaOffsetsGapFree(t *testing.T) {
+	c := newCluster(t, sim.Config{Seed: 9, Latency: 5 * time.Millisecond}, 3, topology.Mesh{})
+
+	// Every node appends to the same key; offsets must come out 0..n-1
+	for i := 0; i < 9; i++ {
+		c.inject(c.ids[i%3], protocol.SendReq{Type: "send", Key: "k1", Msg: i})
+	}
+	c.net.RunFor(time.Second)
+
+	var offsets []int
+	for _, r := range c.net.Replies("c1") {
+		var ok protocol.SendOK
+		require.NoError(t, json.Unmarshal(r.Body, &ok))
+		require.Equal(t, "send_ok", ok.Type)
+		offsets = append(offsets, ok.Offset)
+	}
+	slices.Sort(offsets)
+	assert.Equal(t, []int{0, 1, 2, 3, 4, 5, 6, 7, 8}, offsets)
+}
+
+func TestSim_TxnReplicates(t *testing.T) {
+	c := newCluster(t, sim.Config{Seed: 13, Latency: 10 * time.Millisecond}, 3, topology.Mesh{})
+
+	v := 42
+	c.inject("n0", protocol.TxnReq{Type: "txn", Txn: []protocol.TxnOp{{Op: "w", Key: 1, Value: &v}}})
+	ok := c.net.RunUntil(func() bool {
+		for _, s := range c.servers {
+			if s.Txns.Len() != 1 {
+				return false
+			}
+		}
+		return true
+	}, 5*time.Second)
+	require.True(t, ok, "write set did not replicate")
+
+	out := c.servers["n2"].Txns.Execute([]protocol.TxnOp{{Op: "r", Key: 1}})
+	require.NotNil(t, out[0].Value)
+	assert.Equal(t, 42, *out[0].Value)
+
+	// Once every neighbour has acknowledged it the write set is trimmed
+	c.net.RunFor(time.Second)
+	for id, s := range c.servers {
+		sets, _ := s.Txns.Since(0, 0)
+		assert.Empty(t, sets, "%s kept a write set every neighbour holds", id)
+	}
+}
+
+func TestSim_TxnRejectsWriteWithoutValue(t *testing.T) {
+	c := newCluster(t, sim.Config{Seed: 13}, 1, topology.Mesh{})
+
+	c.inject("n0", protocol.TxnReq{Type: "txn", Txn: []protocol.TxnOp{{Op: "w", Key: 1}}})
+	c.net.RunFor(time.Second)
+	replies := c.net.Replies("c1")
+	require.Len(t, replies, 1)
+	var failed protocol.ErrorReply
+	require.NoError(t, json.Unmarshal(replies[0].Body, &failed))
+	assert.Equal(t, protocol.MalformedRequest, failed.Code)
+	assert.Zero(t, c.servers["n0"].Txns.Len())
+}
+
+func TestSim_EchoReturnsPayload(t *testing.T) {
+	c := newCluster(t, sim.Config{Seed: 13}, 1, topology.Mesh{})
+
+	c.inject("n0", protocol.EchoReq{Type: "echo", Echo: "hello"})
+	c.net.RunFor(time.Second)
+	replies := c.net.Replies("c1")
+	require.Len(t, replies, 1)
+	var ok protocol.EchoOK
+	require.NoError(t, json.Unmarshal(replies[0].Body, &ok))
+	assert.Equal(t, "echo_ok", ok.Type)
+	assert.Equal(t, "hello", ok.Echo)
+}
+
+func TestSim_GenerateIDsAreUnique(t *testing.T) {
+	for _, scheme := range []idgen.Scheme{idgen.CounterScheme, idgen.SnowflakeScheme} {
+		t.Run(string(scheme), func(t *testing.T) {
+			ids := []string{"n0", "n1", "n2"}
+			net := sim.NewNetwork(sim.Config{Seed: 19, Latency: time.Millisecond}, ids)
+			for _, id := range ids {
+				node := net.Node(id)
+				s := NewServer(node)
+				s.Clock = net
+				s.IDScheme = scheme
+				s.Register(node)
+			}
+			net.Init()
+
+			for i := 0; i < 300; i++ {
+				net.Inject("c1", ids[i%3], map[string]any{"type": "generate", "msg_id": i + 1})
+			}
+			net.RunFor(time.Second)
+
+			seen := make(map[string]bool)
+			for _, r := range net.Replies("c1") {
+				var ok struct {
+					Type string          `json:"type"`
+					ID   json.RawMessage `json:"id"`
+				}
+				require.NoError(t, json.Unmarshal(r.Body, &ok))
+				require.Equal(t, "generate_ok", ok.Type)
+				require.False(t, seen[string(ok.ID)], "duplicate id %s", ok.ID)
+				seen[string(ok.ID)] = true
+			}
+			assert.Len(t, seen, 300)
+		})
+	}
+}
+
+func TestSim_WALSurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+
+	// boot starts a single node with a WAL and asks it for two IDs
+	boot := func(seed uint64) (*Server, []string) {
+		net := sim.NewNetwork(sim.Config{Seed: seed}, []string{"n0"})
+		node := net.Node("n0")
+		s := NewServer(node, WithWAL(dir))
+		s.Clock = net
+		s.IDBlock = 10
+		s.CompactEvery = 5
+		s.Register(node)
+		net.Init()
+
+		net.Inject("c1", "n0", map[string]any{"type": "topology", "msg_id": 1})
+		for i := 0; i < 8; i++ {
+			net.Inject("c1", "n0", map[string]any{"type": "broadcast", "message": int(seed)*100 + i, "msg_id": 10 + i})
+		}
+		net.Inject("c1", "n0", map[string]any{"type": "generate", "msg_id": 100})
+		net.Inject("c1", "n0", map[string]any{"type": "generate", "msg_id": 101})
+		net.RunFor(time.Second)
+
+		var ids []string
+		for _, r := range net.Replies("c1") {
+			var ok protocol.GenerateOK
+			require.NoError(t, json.Unmarshal(r.Body, &ok))
+			if ok.Type == "generate_ok" {
+				ids = append(ids, ok.ID.(string))
+			}
+		}
+		require.NoError(t, s.WAL.Close())
+		return s, ids
+	}
+
+	_, first := boot(1)
+	s, second := boot(2)
+
+	assert.ElementsMatch(t, []string{"n0_1", "n0_2"}, first)
+	assert.ElementsMatch(t, []string{"n0_11", "n0_12"}, second, "restart resumes after the reserved block")
+
+	want := []int{100, 101, 102, 103, 104, 105, 106, 107, 200, 201, 202, 203, 204, 205, 206, 207}
+	assert.Equal(t, want, s.Messages.Snapshot())
+	assert.Len(t, s.Versions.Since(nil, 0), len(want), "recovered values are tagged afresh")
+}
+
+func TestSim_WALWriteFailureRefusesBroadcast(t *testing.T) {
+	net := sim.NewNetwork(sim.Config{Seed: 1}, []string{"n0"})
+	node := net.Node("n0")
+	s := NewServer(node, WithWAL(t.TempDir()))
+	s.Clock = net
+	s.Register(node)
+	net.Init()
+
+	net.Inject("c1", "n0", map[string]any{"type": "broadcast", "message": 1, "msg_id": 1})
+	net.RunFor(time.Second)
+	require.NoError(t, s.WAL.Close())
+	net.Inject("c1", "n0", map[string]any{"type": "broadcast", "message": 2, "msg_id": 2})
+	net.RunFor(time.Second)
+
+	replies := net.Replies("c1")
+	require.Len(t, replies, 2)
+	var failed protocol.ErrorReply
+	require.NoError(t, json.Unmarshal(replies[1].Body, &failed))
+	assert.Equal(t, protocol.TemporarilyUnavailable, failed.Code)
+	assert.Equal(t, []int{1}, s.Messages.Snapshot(), "a value that was not persisted is not kept")
+}
+
+func TestSim_WALCompactFailureKeepsBroadcast(t *testing.T) {
+	dir := t.TempDir()
+	net := sim.NewNetwork(sim.Config{Seed: 1}, []string{"n0"})
+	node := net.Node("n0")
+	s := NewServer(node, WithWAL(dir))
+	s.Clock = net
+	s.CompactEvery = 1
+	s.Register(node)
+	net.Init()
+
+	// A directory in the way of the snapshot makes every compaction fail
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "n0", "snapshot.json.tmp"), 0o755))
+
+	net.Inject("c1", "n0", map[string]any{"type": "broadcast", "message": 1, "msg_id": 1})
+	net.RunFor(time.Second)
+
+	replies := net.Replies("c1")
+	require.Len(t, replies, 1)
+	var ok protocol.BroadcastOK
+	require.NoError(t, json.Unmarshal(replies[0].Body, &ok))
+	assert.Equal(t, "broadcast_ok", ok.Type, "the value is in the log even if compaction failed")
+	assert.Equal(t, []int{1}, s.Messages.Snapshot())
+
+	require.NoError(t, s.WAL.Close())
+	l, err := wal.Open(filepath.Join(dir, "n0"))
+	require.NoError(t, err)
+	defer l.Close()
+	assert.Equal(t, []int{1}, l.Recovered().Messages)
+}
+
+// TestSim_ConcurrentHandlersAndTopologyChanges drives handlers, gossip
+// rounds and topology changes from separate goroutines while the network
+// delivers deltas and acks, so go test -race checks the peer actors.
+func TestSim_ConcurrentHandlersAndTopologyChanges(t *testing.T) {
+	c := newCluster(t, sim.Config{Seed: 31, Latency: 5 * time.Millisecond}, 4, topology.Given{})
+
+	mesh, ring := protocol.Topology{}, protocol.Topology{}
+	for i, id := range c.ids {
+		for _, other := range c.ids {
+			if other != id {
+				mesh[id] = append(mesh[id], other)
+			}
+		}
+		ring[id] = []string{c.ids[(i+1)%len(c.ids)], c.ids[(i+len(c.ids)-1)%len(c.ids)]}
+	}
+
+	// Build every request up front; c.request is not safe for concurrent use
+	var want []int
+	broadcasts := make(map[string][]maelstrom.Message)
+	for i, id := range c.ids {
+		for j := 0; j < 50; j++ {
+			v := i*100 + j
+			want = append(want, v)
+			broadcasts[id] = append(broadcasts[id], c.request(id, protocol.BroadcastReq{Type: "broadcast", Message: v}))
+		}
+	}
+	slices.Sort(want)
+	var reshapes []maelstrom.Message
+	for k := 0; k < 20; k++ {
+		shape := mesh
+		if k%2 == 1 {
+			shape = ring
+		}
+		for _, id := range c.ids {
+			reshapes = append(reshapes, c.request(id, protocol.TopologyReq{Type: "topology", Topology: shape}))
+		}
+	}
+
+	var wg sync.WaitGroup
+	for _, id := range c.ids {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, msg := range broadcasts[id] {
+				assert.NoError(t, c.servers[id].HandleBroadcast(msg))
+			}
+		}()
+	}
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for k := 0; k < 50; k++ {
+			for _, id := range c.ids {
+				assert.NoError(t, c.servers[id].Tick())
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for _, msg := range reshapes {
+			assert.NoError(t, c.servers[msg.Dest].HandleTopology(msg))
+		}
+	}()
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	for running := true; running; {
+		select {
+		case <-done:
+			running = false
+		default:
+			c.net.RunFor(time.Millisecond)
+		}
+	}
+
+	// Settle on the mesh so every node ends up with every value
+	for _, id := range c.ids {
+		require.NoError(t, c.servers[id].HandleTopology(c.request(id, protocol.TopologyReq{Type: "topology", Topology: mesh})))
+	}
+	ok := c.net.RunUntil(func() bool { return c.converged(want) }, 10*time.Second)
+	assert.True(t, ok, "cluster did not converge")
+}
+
+func TestSim_IdleClusterSendsNoProbes(t *testing.T) {
+	c := newCluster(t, sim.Config{Seed: 5, Latency: 10 * time.Millisecond}, 5, topology.Mesh{},
+		func(s *Server) { s.AntiEntropyInterval = 0 })
+	c.topology()
+
+	want := c.broadcast(10, 10*time.Millisecond)
+	require.True(t, c.net.RunUntil(func() bool { return c.converged(want) }, 5*time.Second))
+	c.net.RunFor(time.Second)
+
+	// With every delta acknowledged there is nothing to probe anyone about
+	sent := c.net.Stats().Sent
+	c.net.RunFor(5 * time.Second)
+	assert.Equal(t, sent, c.net.Stats().Sent)
+	for _, s := range c.servers {
+		for _, id := range c.ids {
+			assert.True(t, s.reachable(id))
+		}
+	}
+}
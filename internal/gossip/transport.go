@@ -0,0 +1,68 @@
+package gossip
+
+import (
+	// --- Standard Lib ---
+	"context"
+
+	// --- Third Party ---
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+)
+
+// Transport is the network a Server runs on. *maelstrom.Node satisfies it
+// over stdin/stdout; tests substitute an in-process simulated network.
+type Transport interface {
+	// ID returns this node's ID; only valid after init
+	ID() string
+
+	// NodeIDs returns every node in the cluster, including this one
+	NodeIDs() []string
+
+	// Handle registers the handler for a message type
+	Handle(typ string, fn maelstrom.HandlerFunc)
+
+	// Send delivers a message body to dest without waiting for a reply
+	Send(dest string, body any) error
+
+	// Reply responds to req, setting in_reply_to from its msg_id
+	Reply(req maelstrom.Message, body any) error
+
+	// SyncRPC sends body to dest and blocks until the reply or ctx is done
+	SyncRPC(ctx context.Context, dest string, body any) (maelstrom.Message, error)
+}
+
+// Register installs every Server handler on t. Handlers run on whatever
+// goroutine t delivers messages from; each received message is counted
+// in Metrics and traced by the handler logger before its handler runs.
+func (s *Server) Register(t Transport) {
+	route := func(typ string, fn maelstrom.HandlerFunc) {
+		t.Handle(typ, s.metered(typ, s.traced(typ, fn)))
+	}
+
+	route("init", s.HandleInit)
+	route("echo", s.HandleEcho)
+	route("generate", s.HandleGenerate)
+	route("broadcast", s.HandleBroadcast)
+	route("add", s.HandleAdd)
+	route("read", s.HandleRead)
+	route("send", s.HandleSend)
+	route("poll", s.HandlePoll)
+	route("commit_offsets", s.HandleCommitOffsets)
+	route("list_committed_offsets", s.HandleListCommittedOffsets)
+	route("txn", s.HandleTxn)
+	route("topology", s.HandleTopology)
+	route("delta", s.HandleDelta)
+	route("delta_ok", s.HandleDeltaOK)
+	route("digest", s.HandleDigest)
+	route("digest_ok", s.HandleDigestOK)
+	route("push", s.HandlePush)
+	route("push_ok", s.HandlePushOK)
+	route("pull_ok", s.HandlePullOK)
+	route("ihave", s.HandleIHave)
+	route("graft", s.HandleGraft)
+	route("prune", s.HandlePrune)
+	route("probe", s.HandleProbe)
+	route("probe_ok", s.HandleProbeOK)
+	route("probe_req", s.HandleIndirectProbe)
+	route("stats", s.HandleStats)
+	route("error", s.HandleError)
+}
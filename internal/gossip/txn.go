@@ -0,0 +1,48 @@
+package gossip
+
+import (
+	// --- Internal Lib ---
+	"maelstrom-broadcast/internal/protocol"
+
+	// --- Third Party ---
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+)
+
+// HandleTxn executes a transaction atomically against the local replica.
+// The node never coordinates with peers before replying, so it stays available
+// under partitions; committed writes reach peers through delta gossip.
+// Transactions with operations other than reads and writes are rejected
+// with not-supported, and writes without a value with malformed-request,
+// before anything runs.
+func (s *Server) HandleTxn(msg maelstrom.Message) error {
+	return handle(msg, func(req protocol.TxnReq) error {
+		for _, op := range req.Txn {
+			if op.Op != "r" && op.Op != "w" {
+				return protocol.Errorf(protocol.NotSupported, "txn op %q", op.Op)
+			}
+			if op.Op == "w" && op.Value == nil {
+				return protocol.Errorf(protocol.MalformedRequest, "txn write to key %d has no value", op.Key)
+			}
+		}
+		resp := protocol.TxnOK{
+			Type: "txn_ok",
+			Txn:  s.Txns.Execute(req.Txn),
+		}
+		return s.Node.Reply(msg, resp)
+	})
+}
+
+// trimTxns drops the replicated write sets every neighbour has acknowledged,
+// dead ones included, so a peer that recovers is still sent what it missed.
+func (s *Server) trimTxns(peers map[string]*peerActor) {
+	if len(peers) == 0 {
+		return
+	}
+	upto := -1
+	for _, a := range peers {
+		if from := a.pq.TxnFrom(); upto < 0 || from < upto {
+			upto = from
+		}
+	}
+	s.Txns.Trim(upto)
+}
@@ -0,0 +1,92 @@
+package gossip
+
+import (
+	// --- Standard Lib ---
+	"path/filepath"
+
+	// --- Internal Lib ---
+	"maelstrom-broadcast/internal/idgen"
+	"maelstrom-broadcast/internal/protocol"
+	"maelstrom-broadcast/internal/wal"
+)
+
+// Option configures a Server at construction time.
+type Option func(*Server)
+
+// WithWAL makes the server durable: broadcast values and counter ID
+// reservations are logged under dir/<node id> and recovered on restart.
+func WithWAL(dir string) Option {
+	return func(s *Server) {
+		s.walDir = dir
+	}
+}
+
+// restore opens the node's write-ahead log and reloads the messages it
+// recorded. The log is keyed by node ID, so this runs from NewServer when
+// the transport already knows its ID and otherwise from init; only the
+// first call has any effect.
+func (s *Server) restore() error {
+	s.walOnce.Do(func() {
+		l, err := wal.Open(filepath.Join(s.walDir, s.Node.ID()))
+		if err != nil {
+			s.walErr = err
+			return
+		}
+		s.Messages.AddAll(l.Recovered().Messages...)
+		s.Messages.OnAdd(s.persistMessage)
+		s.WAL = l
+	})
+	return s.walErr
+}
+
+// newIDs builds the generator for IDScheme. With a WAL, counter IDs are
+// handed out from durably reserved blocks so a restart never reissues one.
+func (s *Server) newIDs() (idgen.Generator, error) {
+	if s.WAL != nil && (s.IDScheme == "" || s.IDScheme == idgen.CounterScheme) {
+		s.blockIDs = idgen.NewBlockCounter(s.Node.ID(), s.WAL.Recovered().IDLimit, s.IDBlock, s.reserveIDs)
+		return s.blockIDs, nil
+	}
+	return idgen.New(s.IDScheme, s.Node.ID(), s.Node.NodeIDs(), s.Clock)
+}
+
+// persistMessage logs a newly seen broadcast value before it becomes visible
+// or is acknowledged. On failure the value is not kept and the request that
+// carried it is refused, so nothing is acknowledged that a restart may lose.
+func (s *Server) persistMessage(v int) error {
+	if err := s.persist(wal.Record{Op: wal.OpMessage, Value: int64(v)}); err != nil {
+		s.logs().wal.Error("persist failed", "message", v, "err", err)
+		return protocol.Errorf(protocol.TemporarilyUnavailable, "persist %d: %v", v, err)
+	}
+	return nil
+}
+
+// reserveIDs logs that counter IDs up to limit may be issued.
+func (s *Server) reserveIDs(limit uint64) error {
+	return s.persist(wal.Record{Op: wal.OpReserveIDs, Value: int64(limit)})
+}
+
+// persist appends r, compacting the log into a snapshot once CompactEvery
+// records have built up. Only a failed append is returned: once r is in the
+// log it is durable, so a failed compaction is logged and left to be
+// retried after a later append.
+func (s *Server) persist(r wal.Record) error {
+	if err := s.WAL.Append(r); err != nil {
+		return err
+	}
+	if s.CompactEvery > 0 && s.WAL.Pending() >= s.CompactEvery {
+		if err := s.WAL.Compact(s.walState); err != nil {
+			s.logs().wal.Error("compact failed", "err", err)
+		}
+	}
+	return nil
+}
+
+// walState captures the state a snapshot must cover, including values
+// still being inserted, whose records may already be in the log.
+func (s *Server) walState() wal.State {
+	st := wal.State{Messages: append(s.Messages.Snapshot(), s.Messages.Inserting()...)}
+	if s.blockIDs != nil {
+		st.IDLimit = s.blockIDs.Reserved()
+	}
+	return st
+}
@@ -0,0 +1,62 @@
+package gossip
+
+import (
+	// --- Standard Lib ---
+	"fmt"
+)
+
+// Workload names the Maelstrom workload a node serves. The broadcast and
+// g-counter workloads share the "read" message type, so it decides which
+// shape read replies take.
+type Workload string
+
+const (
+	// AutoWorkload infers the workload from the first broadcast, topology
+	// or add request received
+	AutoWorkload Workload = ""
+
+	// BroadcastWorkload answers reads with the broadcast values seen
+	BroadcastWorkload Workload = "broadcast"
+
+	// CounterWorkload answers reads with the grow-only counter's value
+	CounterWorkload Workload = "g-counter"
+)
+
+// ParseWorkload maps a workload name ("", "broadcast" or "g-counter") to a
+// Workload; the empty name selects AutoWorkload.
+func ParseWorkload(name string) (Workload, error) {
+	switch w := Workload(name); w {
+	case AutoWorkload, BroadcastWorkload, CounterWorkload:
+		return w, nil
+	default:
+		return "", fmt.Errorf("gossip: unknown workload %q", name)
+	}
+}
+
+// served records that a request belonging to workload w was received. Only
+// the first one counts, and only while Workload is AutoWorkload.
+func (s *Server) served(w Workload) {
+	s.workloadMU.Lock()
+	defer s.workloadMU.Unlock()
+
+	if s.inferred == AutoWorkload {
+		s.inferred = w
+	}
+}
+
+// workload returns the configured Workload or, under AutoWorkload, the one
+// inferred from the requests received so far. A node that has seen none
+// serves the g-counter workload, whose reads may come before any add.
+func (s *Server) workload() Workload {
+	if s.Workload != AutoWorkload {
+		return s.Workload
+	}
+
+	s.workloadMU.Lock()
+	defer s.workloadMU.Unlock()
+
+	if s.inferred == AutoWorkload {
+		return CounterWorkload
+	}
+	return s.inferred
+}
@@ -0,0 +1,262 @@
+// Package health implements a SWIM-style failure detector for gossip peers.
+// Any traffic from a peer counts as proof of life, and only peers that owe a
+// reply are judged at all; those that fall silent are probed directly and, once suspected, indirectly through other peers so a
+// single lossy link does not get a healthy node declared dead. Peers move
+// between alive, suspect and dead based purely on time since last contact,
+// so a healed partition is noticed as soon as one probe gets through.
+package health
+
+import (
+	// --- Standard Lib ---
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"time"
+)
+
+// State is a peer's health as seen by the local detector.
+type State int
+
+const (
+	// Alive peers have been heard from recently
+	Alive State = iota
+
+	// Suspect peers have been silent long enough to warrant indirect probes
+	Suspect
+
+	// Dead peers are skipped for gossip until they answer a probe
+	Dead
+)
+
+// String returns the lowercase name of the state.
+func (s State) String() string {
+	switch s {
+	case Alive:
+		return "alive"
+	case Suspect:
+		return "suspect"
+	case Dead:
+		return "dead"
+	default:
+		return "unknown"
+	}
+}
+
+// Config controls detector timing.
+type Config struct {
+	// ProbeInterval is how long a peer may stay silent before it is probed,
+	// and the minimum gap between probes of the same peer
+	ProbeInterval time.Duration
+
+	// SuspectAfter is the silence after which a peer becomes Suspect
+	SuspectAfter time.Duration
+
+	// DeadAfter is the silence after which a peer becomes Dead
+	DeadAfter time.Duration
+
+	// IndirectProbes is how many other peers are asked to probe a suspect
+	IndirectProbes int
+}
+
+// DefaultConfig returns timings suited to Maelstrom's 100ms-latency tests:
+// a round trip fits comfortably inside ProbeInterval, so only a real
+// partition or crash gets a peer suspected.
+func DefaultConfig() Config {
+	return Config{
+		ProbeInterval:  500 * time.Millisecond,
+		SuspectAfter:   time.Second,
+		DeadAfter:      3 * time.Second,
+		IndirectProbes: 2,
+	}
+}
+
+// Probe asks the caller to check on Target. If Via is empty the probe goes
+// straight to Target; otherwise Via is asked to probe Target on our behalf.
+type Probe struct {
+	Target string
+	Via    string
+}
+
+// member tracks one peer's contact history.
+type member struct {
+	state     State
+	lastAck   time.Time
+	lastProbe time.Time
+
+	// awaited is set while the peer owes a reply, since when it began to
+	awaited bool
+	since   time.Time
+}
+
+// Detector tracks the health of a set of peers.
+type Detector struct {
+	mu  sync.Mutex
+	cfg Config
+	rng *rand.Rand
+
+	// members maps peer IDs to their contact history
+	members map[string]*member
+}
+
+// NewDetector creates a detector with no peers.
+func NewDetector(cfg Config) *Detector {
+	return &Detector{
+		cfg:     cfg,
+		rng:     rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())),
+		members: make(map[string]*member),
+	}
+}
+
+// WithSeed makes the choice of indirect probe relays reproducible.
+// Returns the detector.
+func (d *Detector) WithSeed(seed uint64) *Detector {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.rng = rand.New(rand.NewPCG(seed, seed))
+	return d
+}
+
+// SetPeers replaces the tracked peer set. Existing peers keep their history;
+// new peers start Alive as of now so they are not probed immediately.
+func (d *Detector) SetPeers(ids []string, now time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	next := make(map[string]*member, len(ids))
+	for _, id := range ids {
+		if m, ok := d.members[id]; ok {
+			next[id] = m
+			continue
+		}
+		next[id] = &member{state: Alive, lastAck: now}
+	}
+	d.members = next
+}
+
+// Observe records contact with a peer at now, marking it Alive.
+// Untracked IDs are ignored. Returns true if the peer was previously
+// Suspect or Dead, i.e. it has just recovered.
+func (d *Detector) Observe(id string, now time.Time) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	m, ok := d.members[id]
+	if !ok {
+		return false
+	}
+	recovered := m.state != Alive
+	m.state = Alive
+	if now.After(m.lastAck) {
+		m.lastAck = now
+	}
+	return recovered
+}
+
+// Await records whether a reply from the peer is outstanding. Only awaited
+// peers are aged and probed, and their silence counts from when the wait
+// began; a peer that owes nothing is not judged and goes back to Alive.
+// Untracked IDs are ignored.
+func (d *Detector) Await(id string, awaited bool, now time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	m, ok := d.members[id]
+	if !ok || m.awaited == awaited {
+		return
+	}
+	m.awaited = awaited
+	if awaited {
+		m.since = now
+		return
+	}
+	m.state = Alive
+}
+
+// State returns the current state of a peer. Untracked peers are Alive.
+func (d *Detector) State(id string) State {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if m, ok := d.members[id]; ok {
+		return m.state
+	}
+	return Alive
+}
+
+// LastAck returns when the peer was last heard from, and whether it is tracked.
+func (d *Detector) LastAck(id string) (time.Time, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	m, ok := d.members[id]
+	if !ok {
+		return time.Time{}, false
+	}
+	return m.lastAck, true
+}
+
+// Tick advances the states of awaited peers to now and returns the probes
+// to send. Silent awaited peers get a direct probe at most once per ProbeInterval; suspect
+// and dead peers additionally get up to IndirectProbes probes relayed
+// through randomly chosen alive peers.
+func (d *Detector) Tick(now time.Time) []Probe {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	ids := make([]string, 0, len(d.members))
+	for id := range d.members {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	var probes []Probe
+	for _, id := range ids {
+		m := d.members[id]
+		if !m.awaited {
+			continue
+		}
+		silence := now.Sub(m.lastAck)
+		if m.since.After(m.lastAck) {
+			silence = now.Sub(m.since)
+		}
+
+		switch {
+		case silence >= d.cfg.DeadAfter:
+			m.state = Dead
+		case silence >= d.cfg.SuspectAfter:
+			m.state = Suspect
+		}
+
+		if silence < d.cfg.ProbeInterval || now.Sub(m.lastProbe) < d.cfg.ProbeInterval {
+			continue
+		}
+		m.lastProbe = now
+		probes = append(probes, Probe{Target: id})
+
+		if m.state != Alive {
+			for _, via := range d.relaysLocked(id, ids) {
+				probes = append(probes, Probe{Target: id, Via: via})
+			}
+		}
+	}
+	return probes
+}
+
+// relaysLocked picks up to IndirectProbes alive peers other than target.
+// Caller must hold d.mu; ids must be sorted for reproducibility.
+func (d *Detector) relaysLocked(target string, ids []string) []string {
+	var candidates []string
+	for _, id := range ids {
+		if id != target && d.members[id].state == Alive {
+			candidates = append(candidates, id)
+		}
+	}
+	d.rng.Shuffle(len(candidates), func(i, j int) {
+		candidates[i], candidates[j] = candidates[j], candidates[i]
+	})
+	if len(candidates) > d.cfg.IndirectProbes {
+		candidates = candidates[:d.cfg.IndirectProbes]
+	}
+	return candidates
+}
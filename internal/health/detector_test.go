@@ -0,0 +1,131 @@
+package health
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testConfig() Config {
+	return Config{
+		ProbeInterval:  100 * time.Millisecond,
+		SuspectAfter:   200 * time.Millisecond,
+		DeadAfter:      500 * time.Millisecond,
+		IndirectProbes: 2,
+	}
+}
+
+func TestDetector_NewPeersStartAlive(t *testing.T) {
+	start := time.Unix(0, 0)
+	d := NewDetector(testConfig())
+	d.SetPeers([]string{"n1", "n2"}, start)
+
+	assert.Equal(t, Alive, d.State("n1"))
+	assert.Empty(t, d.Tick(start.Add(50*time.Millisecond)))
+}
+
+func TestDetector_SilentPeerIsProbedDirectly(t *testing.T) {
+	start := time.Unix(0, 0)
+	d := NewDetector(testConfig())
+	d.SetPeers([]string{"n1"}, start)
+	d.Await("n1", true, start)
+
+	probes := d.Tick(start.Add(100 * time.Millisecond))
+	assert.Equal(t, []Probe{{Target: "n1"}}, probes)
+
+	// Not probed again within the same interval
+	assert.Empty(t, d.Tick(start.Add(150*time.Millisecond)))
+}
+
+func TestDetector_SuspectGetsIndirectProbes(t *testing.T) {
+	start := time.Unix(0, 0)
+	d := NewDetector(testConfig()).WithSeed(1)
+	d.SetPeers([]string{"n1", "n2", "n3", "n4"}, start)
+	d.Await("n1", true, start)
+
+	// Everyone but n1 keeps talking
+	now := start.Add(250 * time.Millisecond)
+	for _, id := range []string{"n2", "n3", "n4"} {
+		d.Observe(id, now)
+	}
+
+	probes := d.Tick(now)
+	assert.Equal(t, Suspect, d.State("n1"))
+
+	var direct, indirect int
+	for _, p := range probes {
+		assert.Equal(t, "n1", p.Target)
+		if p.Via == "" {
+			direct++
+		} else {
+			indirect++
+			assert.NotEqual(t, "n1", p.Via)
+		}
+	}
+	assert.Equal(t, 1, direct)
+	assert.Equal(t, 2, indirect)
+}
+
+func TestDetector_DeadAndRecovers(t *testing.T) {
+	start := time.Unix(0, 0)
+	d := NewDetector(testConfig())
+	d.SetPeers([]string{"n1"}, start)
+	d.Await("n1", true, start)
+
+	d.Tick(start.Add(500 * time.Millisecond))
+	assert.Equal(t, Dead, d.State("n1"))
+
+	// Dead peers keep being probed so a healed partition is noticed
+	assert.NotEmpty(t, d.Tick(start.Add(700*time.Millisecond)))
+
+	recovered := d.Observe("n1", start.Add(800*time.Millisecond))
+	assert.True(t, recovered)
+	assert.Equal(t, Alive, d.State("n1"))
+	assert.False(t, d.Observe("n1", start.Add(810*time.Millisecond)))
+}
+
+func TestDetector_IdlePeersAreNotProbed(t *testing.T) {
+	start := time.Unix(0, 0)
+	d := NewDetector(testConfig())
+	d.SetPeers([]string{"n1"}, start)
+
+	assert.Empty(t, d.Tick(start.Add(time.Second)))
+	assert.Equal(t, Alive, d.State("n1"))
+
+	// Silence only counts from when a reply became owed
+	d.Await("n1", true, start.Add(time.Second))
+	assert.Empty(t, d.Tick(start.Add(1050*time.Millisecond)))
+	assert.Equal(t, []Probe{{Target: "n1"}}, d.Tick(start.Add(1100*time.Millisecond)))
+
+	d.Tick(start.Add(2 * time.Second))
+	assert.Equal(t, Dead, d.State("n1"))
+
+	// Nothing owed any more, so nothing to judge the peer by
+	d.Await("n1", false, start.Add(2*time.Second))
+	assert.Equal(t, Alive, d.State("n1"))
+	assert.Empty(t, d.Tick(start.Add(3*time.Second)))
+}
+
+func TestDetector_SetPeersKeepsHistory(t *testing.T) {
+	start := time.Unix(0, 0)
+	d := NewDetector(testConfig())
+	d.SetPeers([]string{"n1"}, start)
+	d.Await("n1", true, start)
+	d.Tick(start.Add(time.Second))
+
+	d.SetPeers([]string{"n1", "n2"}, start.Add(time.Second))
+	assert.Equal(t, Dead, d.State("n1"))
+	assert.Equal(t, Alive, d.State("n2"))
+
+	d.SetPeers([]string{"n2"}, start.Add(time.Second))
+	_, tracked := d.LastAck("n1")
+	assert.False(t, tracked)
+}
+
+func TestDetector_UntrackedPeersIgnored(t *testing.T) {
+	d := NewDetector(testConfig())
+
+	assert.False(t, d.Observe("nX", time.Unix(0, 0)))
+	assert.Equal(t, Alive, d.State("nX"))
+}
@@ -0,0 +1,239 @@
+// Package idgen generates cluster-wide unique IDs for the unique-ids
+// workload without coordination between nodes.
+//
+// Two schemes are offered: the original "<node>_<counter>" strings, and
+// 64-bit Snowflake IDs that sort by creation time and survive restarts
+// because they are derived from the clock rather than an in-memory counter.
+package idgen
+
+import (
+	// --- Standard Lib ---
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Scheme names a generator, as chosen by configuration.
+type Scheme string
+
+const (
+	// CounterScheme produces "<node>_<n>" strings from a per-node counter
+	CounterScheme Scheme = "counter"
+
+	// SnowflakeScheme produces time-ordered 64-bit integers
+	SnowflakeScheme Scheme = "snowflake"
+)
+
+// Snowflake layout, from the most significant bit: one unused sign bit,
+// 41 bits of milliseconds since Epoch, 10 bits of node index and 12 bits
+// of per-millisecond sequence.
+const (
+	TimestampBits = 41
+	NodeBits      = 10
+	SequenceBits  = 12
+
+	MaxNodes    = 1 << NodeBits
+	MaxSequence = 1<<SequenceBits - 1
+)
+
+// Epoch is the zero point for Snowflake timestamps; 41 bits of
+// milliseconds from here last until 2094.
+var Epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// ErrTooManyNodes is returned when a node's index does not fit in NodeBits.
+var ErrTooManyNodes = errors.New("idgen: node index exceeds snowflake node bits")
+
+// Clock supplies the current time; gossip.Clock and the simulated network
+// both satisfy it.
+type Clock interface {
+	Now() time.Time
+}
+
+// Generator issues unique IDs. Implementations are safe for concurrent use.
+type Generator interface {
+	// Next returns a new ID, ready to be placed in a generate_ok body
+	Next() (any, error)
+}
+
+// New builds the generator for scheme on node self of the cluster ids.
+func New(scheme Scheme, self string, ids []string, clock Clock) (Generator, error) {
+	switch scheme {
+	case "", CounterScheme:
+		return NewCounter(self, new(atomic.Uint64)), nil
+	case SnowflakeScheme:
+		idx, err := NodeIndex(self, ids)
+		if err != nil {
+			return nil, err
+		}
+		return NewSnowflake(idx, clock)
+	default:
+		return nil, fmt.Errorf("idgen: unknown scheme %q", scheme)
+	}
+}
+
+// ParseScheme validates a scheme name.
+func ParseScheme(name string) (Scheme, error) {
+	switch s := Scheme(strings.ToLower(name)); s {
+	case CounterScheme, SnowflakeScheme:
+		return s, nil
+	default:
+		return "", fmt.Errorf("idgen: unknown scheme %q", name)
+	}
+}
+
+// NodeIndex returns self's position among the sorted cluster ids, which
+// every node computes identically without coordination.
+func NodeIndex(self string, ids []string) (int, error) {
+	sorted := slices.Clone(ids)
+	slices.Sort(sorted)
+	idx, ok := slices.BinarySearch(sorted, self)
+	if !ok {
+		return 0, fmt.Errorf("idgen: node %q not in cluster %v", self, ids)
+	}
+	if idx >= MaxNodes {
+		return 0, ErrTooManyNodes
+	}
+	return idx, nil
+}
+
+// Counter issues "<node>_<n>" IDs from an atomic counter. Unique while the
+// process lives, but restarts from 1 and is neither numeric nor sortable.
+type Counter struct {
+	node string
+	n    *atomic.Uint64
+}
+
+// NewCounter creates a counter generator for node backed by n, so the
+// caller can share or seed the counter.
+func NewCounter(node string, n *atomic.Uint64) *Counter {
+	return &Counter{node: node, n: n}
+}
+
+// Next returns the next "<node>_<n>" string.
+func (c *Counter) Next() (any, error) {
+	return fmt.Sprintf("%s_%d", c.node, c.n.Add(1)), nil
+}
+
+// BlockCounter issues "<node>_<n>" IDs like Counter, but only from blocks
+// reserved in advance through a durable reserve callback. After a restart
+// it resumes from the last reserved limit, so IDs are never reissued; at
+// worst the unused rest of a block is skipped.
+type BlockCounter struct {
+	mu    sync.Mutex
+	node  string
+	size  uint64
+	next  uint64
+	limit uint64
+
+	// reserved is the highest limit ever requested, published before the
+	// reservation is written so a concurrent snapshot never falls behind
+	reserved atomic.Uint64
+
+	reserve func(limit uint64) error
+}
+
+// NewBlockCounter creates a block counter for node that issues IDs above
+// start, reserving size IDs at a time through reserve.
+func NewBlockCounter(node string, start, size uint64, reserve func(limit uint64) error) *BlockCounter {
+	c := &BlockCounter{
+		node:    node,
+		size:    max(size, 1),
+		next:    start + 1,
+		limit:   start,
+		reserve: reserve,
+	}
+	c.reserved.Store(start)
+	return c
+}
+
+// Next returns the next ID, first reserving a new block if the current one
+// is used up. Fails without issuing an ID if the reservation fails.
+func (c *BlockCounter) Next() (any, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.next > c.limit {
+		limit := c.limit + c.size
+		c.reserved.Store(limit)
+		if err := c.reserve(limit); err != nil {
+			return nil, err
+		}
+		c.limit = limit
+	}
+	id := c.next
+	c.next++
+	return fmt.Sprintf("%s_%d", c.node, id), nil
+}
+
+// Reserved returns the highest ID that may have been issued, for snapshots.
+func (c *BlockCounter) Reserved() uint64 {
+	return c.reserved.Load()
+}
+
+// Snowflake issues 64-bit IDs laid out as timestamp|node|sequence.
+//
+// IDs from one generator strictly increase. If the clock steps backwards
+// the generator keeps using the last timestamp it issued, and if the
+// sequence for a millisecond runs out it moves on to the next millisecond
+// early instead of blocking; either way it rejoins the real clock once the
+// clock catches up.
+type Snowflake struct {
+	mu    sync.Mutex
+	node  int64
+	clock Clock
+
+	// lastMS is the timestamp of the last ID issued, in ms since Epoch
+	lastMS int64
+
+	// seq is the sequence number of the last ID issued within lastMS
+	seq int64
+}
+
+// NewSnowflake creates a Snowflake generator for the given node index.
+func NewSnowflake(node int, clock Clock) (*Snowflake, error) {
+	if node < 0 || node >= MaxNodes {
+		return nil, ErrTooManyNodes
+	}
+	return &Snowflake{node: int64(node), clock: clock, lastMS: -1}, nil
+}
+
+// Next returns the next ID as an int64.
+func (s *Snowflake) Next() (any, error) {
+	return s.NextID(), nil
+}
+
+// NextID returns the next ID.
+func (s *Snowflake) NextID() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Clocks before Epoch, such as a simulation's, count as Epoch
+	now := max(0, s.clock.Now().Sub(Epoch).Milliseconds())
+	switch {
+	case now > s.lastMS:
+		s.lastMS = now
+		s.seq = 0
+	case s.seq < MaxSequence:
+		// Same millisecond, or the clock went backwards: stay on lastMS
+		s.seq++
+	default:
+		// Sequence exhausted: borrow the next millisecond
+		s.lastMS++
+		s.seq = 0
+	}
+	return Compose(s.lastMS, s.node, s.seq)
+}
+
+// Compose packs a Snowflake ID from its parts.
+func Compose(ms, node, seq int64) int64 {
+	return ms<<(NodeBits+SequenceBits) | node<<SequenceBits | seq
+}
+
+// Decompose splits a Snowflake ID into its parts.
+func Decompose(id int64) (ms, node, seq int64) {
+	return id >> (NodeBits + SequenceBits), id >> SequenceBits & (MaxNodes - 1), id & MaxSequence
+}
@@ -0,0 +1,243 @@
+package idgen
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"testing/quick"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock is a clock tests move by hand, in either direction.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestComposeDecompose(t *testing.T) {
+	id := Compose(123456, 7, 42)
+	ms, node, seq := Decompose(id)
+	assert.Equal(t, int64(123456), ms)
+	assert.Equal(t, int64(7), node)
+	assert.Equal(t, int64(42), seq)
+
+	max := Compose(1<<TimestampBits-1, MaxNodes-1, MaxSequence)
+	assert.Greater(t, max, int64(0), "layout must leave the sign bit clear")
+}
+
+func TestNodeIndex(t *testing.T) {
+	ids := []string{"n2", "n0", "n1"}
+
+	idx, err := NodeIndex("n2", ids)
+	require.NoError(t, err)
+	assert.Equal(t, 2, idx)
+
+	_, err = NodeIndex("n9", ids)
+	assert.Error(t, err)
+
+	many := make([]string, MaxNodes+1)
+	for i := range many {
+		many[i] = fmt.Sprintf("n%04d", i)
+	}
+	_, err = NodeIndex(many[MaxNodes], many)
+	assert.ErrorIs(t, err, ErrTooManyNodes)
+}
+
+func TestSnowflake_SequenceWithinMillisecond(t *testing.T) {
+	clock := &fakeClock{now: Epoch.Add(time.Second)}
+	g, err := NewSnowflake(3, clock)
+	require.NoError(t, err)
+
+	a, b := g.NextID(), g.NextID()
+	_, _, seqA := Decompose(a)
+	_, _, seqB := Decompose(b)
+	assert.Equal(t, int64(0), seqA)
+	assert.Equal(t, int64(1), seqB)
+
+	clock.Advance(time.Millisecond)
+	c := g.NextID()
+	ms, node, seq := Decompose(c)
+	assert.Equal(t, int64(1001), ms)
+	assert.Equal(t, int64(3), node)
+	assert.Equal(t, int64(0), seq)
+}
+
+func TestSnowflake_ClockRegressionStaysMonotonic(t *testing.T) {
+	clock := &fakeClock{now: Epoch.Add(time.Hour)}
+	g, _ := NewSnowflake(0, clock)
+
+	before := g.NextID()
+	clock.Advance(-time.Minute)
+	after := g.NextID()
+	assert.Greater(t, after, before)
+
+	ms, _, _ := Decompose(after)
+	assert.Equal(t, time.Hour.Milliseconds(), ms, "keeps the last timestamp issued")
+}
+
+func TestSnowflake_SequenceExhaustionBorrowsNextMillisecond(t *testing.T) {
+	clock := &fakeClock{now: Epoch}
+	g, _ := NewSnowflake(0, clock)
+
+	var last int64 = -1
+	for i := 0; i <= MaxSequence+1; i++ {
+		id := g.NextID()
+		require.Greater(t, id, last)
+		last = id
+	}
+	ms, _, seq := Decompose(last)
+	assert.Equal(t, int64(1), ms)
+	assert.Equal(t, int64(0), seq)
+}
+
+func TestCounter(t *testing.T) {
+	g := NewCounter("n1", new(atomic.Uint64))
+
+	a, _ := g.Next()
+	b, _ := g.Next()
+	assert.Equal(t, "n1_1", a)
+	assert.Equal(t, "n1_2", b)
+}
+
+func TestNew(t *testing.T) {
+	clock := &fakeClock{now: Epoch}
+	ids := []string{"n0", "n1"}
+
+	g, err := New(CounterScheme, "n1", ids, clock)
+	require.NoError(t, err)
+	assert.IsType(t, &Counter{}, g)
+
+	g, err = New(SnowflakeScheme, "n1", ids, clock)
+	require.NoError(t, err)
+	assert.IsType(t, &Snowflake{}, g)
+
+	_, err = New("uuid", "n1", ids, clock)
+	assert.Error(t, err)
+
+	s, err := ParseScheme("Snowflake")
+	require.NoError(t, err)
+	assert.Equal(t, SnowflakeScheme, s)
+}
+
+// Property: however the nodes' calls interleave and however each node's
+// clock jumps around, no two IDs issued across the cluster are equal and
+// each node's IDs strictly increase.
+func TestSnowflake_PropertyUniqueAcrossNodes(t *testing.T) {
+	prop := func(seed int64, nodes uint8, calls uint16) bool {
+		rng := rand.New(rand.NewSource(seed))
+		n := int(nodes)%16 + 1
+
+		clocks := make([]*fakeClock, n)
+		gens := make([]*Snowflake, n)
+		last := make([]int64, n)
+		for i := range gens {
+			clocks[i] = &fakeClock{now: Epoch.Add(time.Duration(rng.Intn(1000)) * time.Millisecond)}
+			gens[i], _ = NewSnowflake(i, clocks[i])
+			last[i] = -1
+		}
+
+		seen := make(map[int64]struct{})
+		for c := 0; c < int(calls)%5000+1; c++ {
+			i := rng.Intn(n)
+			// Mostly stand still, sometimes step forward or back
+			switch rng.Intn(10) {
+			case 0:
+				clocks[i].Advance(time.Duration(rng.Intn(5)) * time.Millisecond)
+			case 1:
+				clocks[i].Advance(-time.Duration(rng.Intn(50)) * time.Millisecond)
+			}
+
+			id := gens[i].NextID()
+			if _, dup := seen[id]; dup || id <= last[i] {
+				return false
+			}
+			if _, node, _ := Decompose(id); node != int64(i) {
+				return false
+			}
+			seen[id] = struct{}{}
+			last[i] = id
+		}
+		return true
+	}
+	require.NoError(t, quick.Check(prop, &quick.Config{MaxCount: 200}))
+}
+
+// Property: concurrent callers on one node never receive the same ID.
+func TestSnowflake_PropertyConcurrentCallers(t *testing.T) {
+	clock := &fakeClock{now: Epoch}
+	g, _ := NewSnowflake(1, clock)
+
+	const workers, each = 8, 2000
+	out := make(chan int64, workers*each)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < each; i++ {
+				if i%100 == 0 {
+					clock.Advance(time.Millisecond)
+				}
+				out <- g.NextID()
+			}
+		}()
+	}
+	wg.Wait()
+	close(out)
+
+	seen := make(map[int64]struct{}, workers*each)
+	for id := range out {
+		_, dup := seen[id]
+		require.False(t, dup, "duplicate id %d", id)
+		seen[id] = struct{}{}
+	}
+}
+
+func TestBlockCounter_ReservesAheadAndResumes(t *testing.T) {
+	var reservations []uint64
+	reserve := func(limit uint64) error {
+		reservations = append(reservations, limit)
+		return nil
+	}
+
+	g := NewBlockCounter("n0", 0, 10, reserve)
+	for i := 1; i <= 11; i++ {
+		id, err := g.Next()
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("n0_%d", i), id)
+	}
+	assert.Equal(t, []uint64{10, 20}, reservations)
+	assert.Equal(t, uint64(20), g.Reserved())
+
+	// A restart resumes after the last reservation, skipping 12..20
+	g = NewBlockCounter("n0", g.Reserved(), 10, reserve)
+	id, _ := g.Next()
+	assert.Equal(t, "n0_21", id)
+}
+
+func TestBlockCounter_FailedReservationIssuesNothing(t *testing.T) {
+	fail := errors.New("disk full")
+	g := NewBlockCounter("n0", 0, 10, func(uint64) error { return fail })
+
+	id, err := g.Next()
+	assert.ErrorIs(t, err, fail)
+	assert.Nil(t, id)
+}
@@ -0,0 +1,151 @@
+// Package kv provides a typed client for Maelstrom's built-in key/value
+// services (seq-kv, lin-kv and lww-kv), plus an in-memory fake of those
+// services so handlers that depend on them can be unit tested.
+package kv
+
+import (
+	// --- Standard Lib ---
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	// --- Internal Lib ---
+	"maelstrom-broadcast/internal/protocol"
+
+	// --- Third Party ---
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+)
+
+// Service names understood by Maelstrom.
+const (
+	SeqKV = "seq-kv"
+	LinKV = "lin-kv"
+	LWWKV = "lww-kv"
+)
+
+// DefaultTimeout bounds calls made with a context that has no deadline, so a
+// dropped reply during a partition cannot wedge a handler forever.
+const DefaultTimeout = time.Second
+
+var (
+	// ErrKeyDoesNotExist is returned when reading or swapping a missing key
+	ErrKeyDoesNotExist = errors.New("kv: key does not exist")
+
+	// ErrPreconditionFailed is returned when a compare-and-swap's from value
+	// does not match the current value
+	ErrPreconditionFailed = errors.New("kv: precondition failed")
+)
+
+// RPCer is the subset of *maelstrom.Node the client needs to reach a service.
+type RPCer interface {
+	SyncRPC(ctx context.Context, dest string, body any) (maelstrom.Message, error)
+}
+
+// Client issues requests against a single Maelstrom KV service.
+type Client struct {
+	rpc     RPCer
+	service string
+
+	// Timeout is applied to calls whose context carries no deadline
+	Timeout time.Duration
+}
+
+// New creates a client for the named service using rpc as transport.
+func New(rpc RPCer, service string) *Client {
+	return &Client{
+		rpc:     rpc,
+		service: service,
+		Timeout: DefaultTimeout,
+	}
+}
+
+// Service returns the name of the KV service this client talks to.
+func (c *Client) Service() string {
+	return c.service
+}
+
+// Read returns the raw JSON value stored under key.
+// Returns an error wrapping ErrKeyDoesNotExist if the key is missing.
+func (c *Client) Read(ctx context.Context, key string) (json.RawMessage, error) {
+	var resp protocol.KVReadOK
+	if err := c.call(ctx, key, protocol.KVReadReq{Type: "read", Key: key}, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Value, nil
+}
+
+// ReadInto reads the value under key and decodes it into v.
+func (c *Client) ReadInto(ctx context.Context, key string, v any) error {
+	raw, err := c.Read(ctx, key)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return fmt.Errorf("kv: decode %s/%s: %w", c.service, key, err)
+	}
+	return nil
+}
+
+// ReadInt reads an integer value stored under key.
+func (c *Client) ReadInt(ctx context.Context, key string) (int, error) {
+	var v int
+	if err := c.ReadInto(ctx, key, &v); err != nil {
+		return 0, err
+	}
+	return v, nil
+}
+
+// Write unconditionally stores value under key.
+func (c *Client) Write(ctx context.Context, key string, value any) error {
+	return c.call(ctx, key, protocol.KVWriteReq{Type: "write", Key: key, Value: value}, nil)
+}
+
+// CompareAndSwap replaces the value under key with to if it currently equals
+// from. With createIfNotExists a missing key is created with to instead of
+// failing. Returns an error wrapping ErrPreconditionFailed on mismatch.
+func (c *Client) CompareAndSwap(ctx context.Context, key string, from, to any, createIfNotExists bool) error {
+	return c.call(ctx, key, protocol.KVCasReq{
+		Type:              "cas",
+		Key:               key,
+		From:              from,
+		To:                to,
+		CreateIfNotExists: createIfNotExists,
+	}, nil)
+}
+
+// call performs a synchronous RPC to the service, applying the default
+// deadline and decoding error replies into the package sentinel errors.
+func (c *Client) call(ctx context.Context, key string, req any, resp any) error {
+	if _, ok := ctx.Deadline(); !ok && c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+
+	msg, err := c.rpc.SyncRPC(ctx, c.service, req)
+	if err != nil {
+		return decodeError(c.service, key, err)
+	}
+	if resp == nil {
+		return nil
+	}
+	if err := json.Unmarshal(msg.Body, resp); err != nil {
+		return fmt.Errorf("kv: decode %s reply: %w", c.service, err)
+	}
+	return nil
+}
+
+// decodeError maps Maelstrom RPC error codes onto the package sentinels.
+// Other errors, including context deadlines, are returned with added context.
+func decodeError(service, key string, err error) error {
+	switch maelstrom.ErrorCode(err) {
+	case maelstrom.KeyDoesNotExist:
+		return fmt.Errorf("%w: %s/%s", ErrKeyDoesNotExist, service, key)
+	case maelstrom.PreconditionFailed:
+		return fmt.Errorf("%w: %s/%s", ErrPreconditionFailed, service, key)
+	default:
+		return fmt.Errorf("kv: %s/%s: %w", service, key, err)
+	}
+}
@@ -0,0 +1,112 @@
+package kv
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_ReadMissingKey(t *testing.T) {
+	c := New(NewFake(), SeqKV)
+
+	_, err := c.ReadInt(context.Background(), "missing")
+	assert.ErrorIs(t, err, ErrKeyDoesNotExist)
+}
+
+func TestClient_WriteThenRead(t *testing.T) {
+	c := New(NewFake(), LinKV)
+	ctx := context.Background()
+
+	require.NoError(t, c.Write(ctx, "a", 42))
+
+	v, err := c.ReadInt(ctx, "a")
+	require.NoError(t, err)
+	assert.Equal(t, 42, v)
+}
+
+func TestClient_ReadInto(t *testing.T) {
+	c := New(NewFake(), LinKV)
+	ctx := context.Background()
+
+	require.NoError(t, c.Write(ctx, "offsets", map[string]int{"k1": 3}))
+
+	var got map[string]int
+	require.NoError(t, c.ReadInto(ctx, "offsets", &got))
+	assert.Equal(t, map[string]int{"k1": 3}, got)
+}
+
+func TestClient_CompareAndSwap(t *testing.T) {
+	c := New(NewFake(), LinKV)
+	ctx := context.Background()
+
+	// Missing key without create fails
+	err := c.CompareAndSwap(ctx, "x", 0, 1, false)
+	assert.ErrorIs(t, err, ErrKeyDoesNotExist)
+
+	// Missing key with create succeeds
+	require.NoError(t, c.CompareAndSwap(ctx, "x", 0, 1, true))
+
+	// Stale from value fails
+	err = c.CompareAndSwap(ctx, "x", 0, 2, false)
+	assert.ErrorIs(t, err, ErrPreconditionFailed)
+
+	// Matching from value succeeds
+	require.NoError(t, c.CompareAndSwap(ctx, "x", 1, 2, false))
+	v, err := c.ReadInt(ctx, "x")
+	require.NoError(t, err)
+	assert.Equal(t, 2, v)
+}
+
+func TestClient_ServicesAreIndependent(t *testing.T) {
+	f := NewFake()
+	seq, lin := New(f, SeqKV), New(f, LinKV)
+	ctx := context.Background()
+
+	require.NoError(t, seq.Write(ctx, "a", 1))
+
+	_, err := lin.ReadInt(ctx, "a")
+	assert.ErrorIs(t, err, ErrKeyDoesNotExist)
+}
+
+func TestClient_ContextDeadline(t *testing.T) {
+	c := New(NewFake(), SeqKV)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	time.Sleep(time.Millisecond)
+
+	err := c.Write(ctx, "a", 1)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+}
+
+func TestClient_ConcurrentCASIncrement(t *testing.T) {
+	c := New(NewFake(), LinKV)
+	ctx := context.Background()
+	var wg sync.WaitGroup
+
+	// Each goroutine retries until its increment lands
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				cur, err := c.ReadInt(ctx, "n")
+				if errors.Is(err, ErrKeyDoesNotExist) {
+					cur = 0
+				}
+				if c.CompareAndSwap(ctx, "n", cur, cur+1, true) == nil {
+					return
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	v, err := c.ReadInt(ctx, "n")
+	require.NoError(t, err)
+	assert.Equal(t, 20, v)
+}
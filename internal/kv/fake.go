@@ -0,0 +1,114 @@
+package kv
+
+import (
+	// --- Standard Lib ---
+	"context"
+	"encoding/json"
+	"reflect"
+	"sync"
+
+	// --- Internal Lib ---
+	"maelstrom-broadcast/internal/protocol"
+
+	// --- Third Party ---
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+)
+
+// Fake is an in-memory stand-in for Maelstrom's KV services.
+// It implements RPCer, so a Client pointed at it exercises the same request
+// encoding and error decoding as it would against a real node. Each service
+// name gets its own independent keyspace; all of them behave linearizably.
+type Fake struct {
+	mu sync.Mutex
+
+	// data maps service name to that service's key/value pairs
+	data map[string]map[string]any
+}
+
+// NewFake creates an empty fake KV service.
+func NewFake() *Fake {
+	return &Fake{
+		data: make(map[string]map[string]any),
+	}
+}
+
+// SyncRPC handles a read, write or cas request addressed to service dest.
+// Honours ctx cancellation to mirror the real node's behaviour.
+func (f *Fake) SyncRPC(ctx context.Context, dest string, body any) (maelstrom.Message, error) {
+	if err := ctx.Err(); err != nil {
+		return maelstrom.Message{}, err
+	}
+
+	buf, err := json.Marshal(body)
+	if err != nil {
+		return maelstrom.Message{}, err
+	}
+	var head maelstrom.MessageBody
+	if err := json.Unmarshal(buf, &head); err != nil {
+		return maelstrom.Message{}, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	store, ok := f.data[dest]
+	if !ok {
+		store = make(map[string]any)
+		f.data[dest] = store
+	}
+
+	var resp any
+	switch head.Type {
+	case "read":
+		var req protocol.KVReadReq
+		if err := json.Unmarshal(buf, &req); err != nil {
+			return maelstrom.Message{}, maelstrom.NewRPCError(maelstrom.MalformedRequest, err.Error())
+		}
+		v, ok := store[req.Key]
+		if !ok {
+			return maelstrom.Message{}, maelstrom.NewRPCError(maelstrom.KeyDoesNotExist, "key does not exist")
+		}
+		raw, _ := json.Marshal(v)
+		resp = protocol.KVReadOK{Type: "read_ok", Value: raw}
+
+	case "write":
+		var req struct {
+			Key   string `json:"key"`
+			Value any    `json:"value"`
+		}
+		if err := json.Unmarshal(buf, &req); err != nil {
+			return maelstrom.Message{}, maelstrom.NewRPCError(maelstrom.MalformedRequest, err.Error())
+		}
+		store[req.Key] = req.Value
+		resp = protocol.KVWriteOK{Type: "write_ok"}
+
+	case "cas":
+		var req struct {
+			Key               string `json:"key"`
+			From              any    `json:"from"`
+			To                any    `json:"to"`
+			CreateIfNotExists bool   `json:"create_if_not_exists"`
+		}
+		if err := json.Unmarshal(buf, &req); err != nil {
+			return maelstrom.Message{}, maelstrom.NewRPCError(maelstrom.MalformedRequest, err.Error())
+		}
+		cur, ok := store[req.Key]
+		switch {
+		case !ok && !req.CreateIfNotExists:
+			return maelstrom.Message{}, maelstrom.NewRPCError(maelstrom.KeyDoesNotExist, "key does not exist")
+		case ok && !reflect.DeepEqual(cur, req.From):
+			return maelstrom.Message{}, maelstrom.NewRPCError(maelstrom.PreconditionFailed, "current value does not match from")
+		}
+		store[req.Key] = req.To
+		resp = protocol.KVCasOK{Type: "cas_ok"}
+
+	default:
+		return maelstrom.Message{}, maelstrom.NewRPCError(maelstrom.NotSupported, head.Type)
+	}
+
+	out, err := json.Marshal(resp)
+	if err != nil {
+		return maelstrom.Message{}, err
+	}
+	return maelstrom.Message{Src: dest, Body: out}, nil
+}
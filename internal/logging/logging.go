@@ -0,0 +1,197 @@
+// Package logging builds the structured loggers nodes write to stderr,
+// where Maelstrom collects them per node.
+//
+// Every logger carries a "component" attribute, and each component can
+// have its own level, so handler tracing can be turned up without drowning
+// in gossip rounds. Debug records, which come from per-message hot paths,
+// can be sampled down to one in N. Everything is configurable through
+// environment variables, so no rebuild is needed to investigate a run.
+package logging
+
+import (
+	// --- Standard Lib ---
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// ComponentKey is the attribute that selects a logger's component level.
+const ComponentKey = "component"
+
+// Environment variables read by FromEnv.
+const (
+	// EnvLevel holds a level spec such as "info,handler=debug,gossip=warn"
+	EnvLevel = "GOSSIP_LOG_LEVEL"
+
+	// EnvSample keeps one debug record in N; 0 or 1 keeps them all
+	EnvSample = "GOSSIP_LOG_SAMPLE"
+
+	// EnvFormat selects "text" (the default) or "json" output
+	EnvFormat = "GOSSIP_LOG_FORMAT"
+)
+
+// Config describes how loggers filter and format records.
+type Config struct {
+	// Level applies to components without their own entry
+	Level slog.Level
+
+	// Components overrides Level per component
+	Components map[string]slog.Level
+
+	// Sample keeps one in Sample debug records; 0 or 1 keeps all
+	Sample int
+
+	// JSON selects JSON output instead of text
+	JSON bool
+}
+
+// DefaultConfig logs info and above from every component, unsampled.
+func DefaultConfig() Config {
+	return Config{Level: slog.LevelInfo}
+}
+
+// ParseLevels parses a spec of comma-separated entries, each either a bare
+// level for every component or component=level, e.g. "warn,handler=debug".
+func ParseLevels(spec string) (Config, error) {
+	cfg := DefaultConfig()
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, lvl, scoped := strings.Cut(entry, "=")
+		if !scoped {
+			lvl = name
+		}
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(lvl)); err != nil {
+			return Config{}, fmt.Errorf("logging: bad level in %q: %w", entry, err)
+		}
+		if !scoped {
+			cfg.Level = level
+			continue
+		}
+		if cfg.Components == nil {
+			cfg.Components = make(map[string]slog.Level)
+		}
+		cfg.Components[strings.TrimSpace(name)] = level
+	}
+	return cfg, nil
+}
+
+// FromEnv builds a Config from EnvLevel, EnvSample and EnvFormat.
+func FromEnv() (Config, error) {
+	cfg, err := ParseLevels(os.Getenv(EnvLevel))
+	if err != nil {
+		return Config{}, err
+	}
+	if s := os.Getenv(EnvSample); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			return Config{}, fmt.Errorf("logging: bad %s %q", EnvSample, s)
+		}
+		cfg.Sample = n
+	}
+	switch f := strings.ToLower(os.Getenv(EnvFormat)); f {
+	case "", "text":
+	case "json":
+		cfg.JSON = true
+	default:
+		return Config{}, fmt.Errorf("logging: bad %s %q", EnvFormat, f)
+	}
+	return cfg, nil
+}
+
+// levelFor returns the effective level of component.
+func (c Config) levelFor(component string) slog.Level {
+	if l, ok := c.Components[component]; ok {
+		return l
+	}
+	return c.Level
+}
+
+// minLevel returns the lowest level any component logs at.
+func (c Config) minLevel() slog.Level {
+	min := c.Level
+	for _, l := range c.Components {
+		if l < min {
+			min = l
+		}
+	}
+	return min
+}
+
+// New creates a logger writing to w according to cfg.
+func New(w io.Writer, cfg Config) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: cfg.minLevel()}
+	var inner slog.Handler
+	if cfg.JSON {
+		inner = slog.NewJSONHandler(w, opts)
+	} else {
+		inner = slog.NewTextHandler(w, opts)
+	}
+	return slog.New(&handler{
+		inner:   inner,
+		cfg:     cfg,
+		level:   cfg.Level,
+		sampled: new(atomic.Uint64),
+	})
+}
+
+// Discard returns a logger that drops everything.
+func Discard() *slog.Logger {
+	return slog.New(slog.DiscardHandler)
+}
+
+// handler filters records by the level of the component named in its
+// attributes and samples debug records.
+type handler struct {
+	inner slog.Handler
+	cfg   Config
+
+	// level is the effective level for this handler's component
+	level slog.Level
+
+	// sampled counts debug records seen, shared by all derived handlers
+	sampled *atomic.Uint64
+}
+
+// Enabled reports whether the component logs at level.
+func (h *handler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level
+}
+
+// Handle passes the record on, dropping all but one in cfg.Sample debug records.
+func (h *handler) Handle(ctx context.Context, r slog.Record) error {
+	if h.cfg.Sample > 1 && r.Level < slog.LevelInfo {
+		if (h.sampled.Add(1)-1)%uint64(h.cfg.Sample) != 0 {
+			return nil
+		}
+	}
+	return h.inner.Handle(ctx, r)
+}
+
+// WithAttrs adds attributes, switching to a component's level when one of
+// them names it.
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	next := *h
+	next.inner = h.inner.WithAttrs(attrs)
+	for _, a := range attrs {
+		if a.Key == ComponentKey {
+			next.level = h.cfg.levelFor(a.Value.String())
+		}
+	}
+	return &next
+}
+
+// WithGroup opens a group on the wrapped handler.
+func (h *handler) WithGroup(name string) slog.Handler {
+	next := *h
+	next.inner = h.inner.WithGroup(name)
+	return &next
+}
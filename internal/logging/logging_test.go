@@ -0,0 +1,85 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLevels(t *testing.T) {
+	cfg, err := ParseLevels("warn, handler=debug,gossip=ERROR")
+	require.NoError(t, err)
+	assert.Equal(t, slog.LevelWarn, cfg.Level)
+	assert.Equal(t, slog.LevelDebug, cfg.levelFor("handler"))
+	assert.Equal(t, slog.LevelError, cfg.levelFor("gossip"))
+	assert.Equal(t, slog.LevelWarn, cfg.levelFor("health"))
+	assert.Equal(t, slog.LevelDebug, cfg.minLevel())
+
+	cfg, err = ParseLevels("")
+	require.NoError(t, err)
+	assert.Equal(t, slog.LevelInfo, cfg.Level)
+
+	_, err = ParseLevels("handler=loud")
+	assert.Error(t, err)
+}
+
+func TestFromEnv(t *testing.T) {
+	t.Setenv(EnvLevel, "debug")
+	t.Setenv(EnvSample, "10")
+	t.Setenv(EnvFormat, "json")
+
+	cfg, err := FromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, slog.LevelDebug, cfg.Level)
+	assert.Equal(t, 10, cfg.Sample)
+	assert.True(t, cfg.JSON)
+
+	t.Setenv(EnvSample, "often")
+	_, err = FromEnv()
+	assert.Error(t, err)
+}
+
+func TestNew_PerComponentLevels(t *testing.T) {
+	var buf bytes.Buffer
+	cfg, _ := ParseLevels("info,handler=debug,gossip=warn")
+	l := New(&buf, cfg)
+
+	l.With(ComponentKey, "handler").Debug("handler debug")
+	l.With(ComponentKey, "gossip").Info("gossip info")
+	l.With(ComponentKey, "gossip").Warn("gossip warn")
+	l.With(ComponentKey, "health").Debug("health debug")
+	l.With(ComponentKey, "health").Info("health info")
+
+	out := buf.String()
+	assert.Contains(t, out, "handler debug")
+	assert.NotContains(t, out, "gossip info")
+	assert.Contains(t, out, "gossip warn")
+	assert.NotContains(t, out, "health debug")
+	assert.Contains(t, out, "health info")
+}
+
+func TestNew_SamplesDebugOnly(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(&buf, Config{Level: slog.LevelDebug, Sample: 10, JSON: true})
+
+	for i := 0; i < 100; i++ {
+		l.Debug("hot", "i", i)
+	}
+	l.Warn("cold")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 11, "10 of 100 debug records plus the warning")
+
+	var first map[string]any
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &first))
+	assert.Equal(t, float64(0), first["i"])
+}
+
+func TestDiscard(t *testing.T) {
+	assert.False(t, Discard().Enabled(t.Context(), slog.LevelError))
+}
@@ -0,0 +1,84 @@
+// Package logstore provides thread-safe storage for the Kafka-style log
+// workload: append-only integer logs addressed by key, with offsets that
+// start at zero and grow by one per append.
+package logstore
+
+import (
+	"hash/fnv"
+	"sync"
+)
+
+// DefaultPollLimit caps how many messages a single poll returns per key,
+// keeping poll_ok payloads bounded for long-running logs.
+const DefaultPollLimit = 100
+
+// Log stores every keyed log owned by this node.
+// Offsets are the index of a message within its key's slice, so they are
+// monotonic and gap-free by construction.
+type Log struct {
+	mu sync.RWMutex
+
+	// logs maps each key to its messages in offset order
+	logs map[string][]int
+}
+
+// New creates an empty log store.
+func New() *Log {
+	return &Log{
+		logs: make(map[string][]int),
+	}
+}
+
+// Append adds msg to the end of key's log and returns its offset.
+func (l *Log) Append(key string, msg int) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.logs[key] = append(l.logs[key], msg)
+	return len(l.logs[key]) - 1
+}
+
+// Poll returns up to limit [offset, message] pairs from key's log starting
+// at offset from. Returns an empty slice if there is nothing at or after from.
+func (l *Log) Poll(key string, from, limit int) [][2]int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	msgs := l.logs[key]
+	if from < 0 {
+		from = 0
+	}
+	if from >= len(msgs) {
+		return [][2]int{}
+	}
+
+	end := len(msgs)
+	if limit > 0 && from+limit < end {
+		end = from + limit
+	}
+	out := make([][2]int, 0, end-from)
+	for off := from; off < end; off++ {
+		out = append(out, [2]int{off, msgs[off]})
+	}
+	return out
+}
+
+// Len returns the number of messages stored under key.
+func (l *Log) Len(key string) int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return len(l.logs[key])
+}
+
+// Owner deterministically picks the node responsible for key from nodes.
+// Every node sees the same node list from Maelstrom, so all of them agree
+// on the owner without coordination. Returns "" if nodes is empty.
+func Owner(key string, nodes []string) string {
+	if len(nodes) == 0 {
+		return ""
+	}
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return nodes[h.Sum32()%uint32(len(nodes))]
+}
@@ -0,0 +1,69 @@
+package logstore
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLog_AppendOffsets(t *testing.T) {
+	l := New()
+
+	assert.Equal(t, 0, l.Append("k1", 10))
+	assert.Equal(t, 1, l.Append("k1", 11))
+	assert.Equal(t, 0, l.Append("k2", 20))
+	assert.Equal(t, 2, l.Len("k1"))
+}
+
+func TestLog_Poll(t *testing.T) {
+	l := New()
+	for i := 0; i < 5; i++ {
+		l.Append("k", i*10)
+	}
+
+	assert.Equal(t, [][2]int{{3, 30}, {4, 40}}, l.Poll("k", 3, 0))
+	assert.Equal(t, [][2]int{{1, 10}, {2, 20}}, l.Poll("k", 1, 2))
+	assert.Equal(t, [][2]int{{0, 0}}, l.Poll("k", -1, 1))
+}
+
+func TestLog_PollPastEnd(t *testing.T) {
+	l := New()
+	l.Append("k", 1)
+
+	assert.Empty(t, l.Poll("k", 5, 0))
+	assert.NotNil(t, l.Poll("missing", 0, 0))
+}
+
+func TestLog_ConcurrentAppendGapFree(t *testing.T) {
+	l := New()
+	var wg sync.WaitGroup
+	offsets := make([]int, 100)
+
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(idx int) {
+			defer wg.Done()
+			offsets[idx] = l.Append("k", idx)
+		}(i)
+	}
+	wg.Wait()
+
+	// Every offset 0..99 must be handed out exactly once
+	seen := make(map[int]bool)
+	for _, off := range offsets {
+		assert.False(t, seen[off])
+		seen[off] = true
+	}
+	assert.Len(t, seen, 100)
+	assert.Len(t, l.Poll("k", 0, 0), 100)
+}
+
+func TestOwner(t *testing.T) {
+	nodes := []string{"n0", "n1", "n2"}
+
+	owner := Owner("some-key", nodes)
+	assert.Contains(t, nodes, owner)
+	assert.Equal(t, owner, Owner("some-key", nodes))
+	assert.Equal(t, "", Owner("some-key", nil))
+}
@@ -0,0 +1,230 @@
+// Package plumtree keeps the per-node state of Plumtree (epidemic broadcast
+// trees, Leitão et al.): which overlay neighbours receive new values eagerly
+// and which only get lazy announcements, plus the values announced to this
+// node that it is still waiting for.
+//
+// Every neighbour starts eager. A neighbour that sends a value this node
+// already had is redundant, so the link is pruned to lazy, unless it only
+// resent what it had delivered before after losing the ack; what remains of
+// the eager links converges on a spanning tree. Lazy neighbours are told
+// which values exist ("ihave"); a value announced but not received within a
+// timeout means the tree is broken, and grafting the announcer back to eager
+// repairs it.
+package plumtree
+
+import (
+	// --- Standard Lib ---
+	"slices"
+	"sync"
+	"time"
+)
+
+// Announcement is a value learned since the last lazy round, along with the
+// neighbour it came from, which need not be told about it.
+type Announcement struct {
+	Value int
+	From  string
+}
+
+// missing is a value announced to this node but not yet received.
+type missing struct {
+	from     string
+	deadline time.Time
+}
+
+// State is the Plumtree state of one node. It is safe for concurrent use.
+type State struct {
+	mu sync.Mutex
+
+	// peers is the current overlay neighbour set; lazy is the subset that
+	// only receives announcements, every other peer is eager
+	peers map[string]bool
+	lazy  map[string]bool
+
+	// announce holds values to announce in the next lazy round
+	announce []Announcement
+
+	// missing tracks announced values this node has not received yet
+	missing map[int]missing
+
+	// batches holds the last batch ID delivered by each neighbour, and
+	// entries the highest sequence number each neighbour has delivered per
+	// origin, so redeliveries can be told apart from redundant pushes
+	batches map[string]uint64
+	entries map[string]map[string]uint64
+}
+
+// New creates an empty state with no neighbours.
+func New() *State {
+	return &State{
+		peers:   make(map[string]bool),
+		lazy:    make(map[string]bool),
+		missing: make(map[int]missing),
+		batches: make(map[string]uint64),
+		entries: make(map[string]map[string]uint64),
+	}
+}
+
+// SetPeers replaces the neighbour set. Neighbours already known keep their
+// eager or lazy status; new ones start eager.
+func (t *State) SetPeers(ids []string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.peers = make(map[string]bool, len(ids))
+	for _, id := range ids {
+		t.peers[id] = true
+	}
+	for id := range t.lazy {
+		if !t.peers[id] {
+			delete(t.lazy, id)
+		}
+	}
+	for id := range t.batches {
+		if !t.peers[id] {
+			delete(t.batches, id)
+		}
+	}
+	for id := range t.entries {
+		if !t.peers[id] {
+			delete(t.entries, id)
+		}
+	}
+}
+
+// Eager reports whether new values are pushed to peer straight away.
+func (t *State) Eager(peer string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.peers[peer] && !t.lazy[peer]
+}
+
+// Lazy returns the lazy neighbours in ID order.
+func (t *State) Lazy() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	out := make([]string, 0, len(t.lazy))
+	for id := range t.lazy {
+		out = append(out, id)
+	}
+	slices.Sort(out)
+	return out
+}
+
+// Prune moves peer to lazy. Reports whether it was eager.
+func (t *State) Prune(peer string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.peers[peer] || t.lazy[peer] {
+		return false
+	}
+	t.lazy[peer] = true
+	return true
+}
+
+// Graft moves peer to eager. Reports whether it was lazy.
+func (t *State) Graft(peer string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.lazy[peer] {
+		return false
+	}
+	delete(t.lazy, peer)
+	return true
+}
+
+// FirstBatch records that peer delivered batch id and reports whether it is
+// new. A sender reuses an ID only to retransmit and assigns the next one
+// once the batch is acked, so any ID other than the last is new. Zero marks
+// a delta without a batch and is never new.
+func (t *State) FirstBatch(peer string, id uint64) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if id == 0 || t.batches[peer] == id {
+		return false
+	}
+	t.batches[peer] = id
+	return true
+}
+
+// FirstEntry records that peer delivered entry seq from origin and reports
+// whether peer had not delivered it before. A sender offers each origin's
+// entries in ascending order, so anything at or below the highest it has
+// delivered is a retransmission.
+func (t *State) FirstEntry(peer, origin string, seq uint64) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	high, ok := t.entries[peer]
+	if !ok {
+		high = make(map[string]uint64)
+		t.entries[peer] = high
+	}
+	if seq <= high[origin] {
+		return false
+	}
+	high[origin] = seq
+	return true
+}
+
+// Received records that v has arrived, from whichever neighbour, so it is
+// announced to the lazy neighbours and no longer awaited.
+func (t *State) Received(v int, from string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.missing, v)
+	t.announce = append(t.announce, Announcement{Value: v, From: from})
+}
+
+// Announcements removes and returns the values to announce this round.
+func (t *State) Announcements() []Announcement {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	out := t.announce
+	t.announce = nil
+	return out
+}
+
+// IHave records that from has the given values, which this node lacks.
+// If they have not arrived by deadline they are grafted from from. A value
+// already awaited keeps its first announcer and deadline.
+func (t *State) IHave(from string, values []int, deadline time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, v := range values {
+		if _, ok := t.missing[v]; !ok {
+			t.missing[v] = missing{from: from, deadline: deadline}
+		}
+	}
+}
+
+// Due removes and returns the awaited values whose deadline has passed at
+// now, grouped by the neighbour that announced them.
+func (t *State) Due(now time.Time) map[string][]int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var out map[string][]int
+	for v, m := range t.missing {
+		if now.Before(m.deadline) {
+			continue
+		}
+		if out == nil {
+			out = make(map[string][]int)
+		}
+		out[m.from] = append(out[m.from], v)
+		delete(t.missing, v)
+	}
+	for _, vs := range out {
+		slices.Sort(vs)
+	}
+	return out
+}
@@ -0,0 +1,83 @@
+package plumtree
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestState_PruneAndGraft(t *testing.T) {
+	s := New()
+	s.SetPeers([]string{"n1", "n2"})
+
+	assert.True(t, s.Eager("n1"), "neighbours start eager")
+	assert.False(t, s.Eager("n9"), "strangers are never eager")
+
+	assert.True(t, s.Prune("n1"))
+	assert.False(t, s.Prune("n1"))
+	assert.False(t, s.Prune("n9"))
+	assert.False(t, s.Eager("n1"))
+	assert.Equal(t, []string{"n1"}, s.Lazy())
+
+	assert.True(t, s.Graft("n1"))
+	assert.False(t, s.Graft("n1"))
+	assert.True(t, s.Eager("n1"))
+	assert.Empty(t, s.Lazy())
+}
+
+func TestState_SetPeersKeepsStatus(t *testing.T) {
+	s := New()
+	s.SetPeers([]string{"n1", "n2"})
+	s.Prune("n1")
+	s.Prune("n2")
+
+	s.SetPeers([]string{"n1", "n3"})
+	assert.Equal(t, []string{"n1"}, s.Lazy())
+	assert.True(t, s.Eager("n3"))
+	assert.False(t, s.Eager("n2"))
+}
+
+func TestState_Announcements(t *testing.T) {
+	s := New()
+	s.Received(1, "n1")
+	s.Received(2, "c1")
+
+	assert.Equal(t, []Announcement{{1, "n1"}, {2, "c1"}}, s.Announcements())
+	assert.Empty(t, s.Announcements())
+}
+
+func TestState_MissingValuesFallDue(t *testing.T) {
+	s := New()
+	start := time.Unix(0, 0)
+	s.IHave("n1", []int{3, 1, 2}, start.Add(time.Second))
+	s.IHave("n2", []int{2, 4}, start.Add(2*time.Second))
+
+	// Receiving a value cancels its wait
+	s.Received(3, "n3")
+
+	assert.Empty(t, s.Due(start))
+	assert.Equal(t, map[string][]int{"n1": {1, 2}}, s.Due(start.Add(time.Second)))
+	assert.Equal(t, map[string][]int{"n2": {4}}, s.Due(start.Add(2*time.Second)))
+	assert.Empty(t, s.Due(start.Add(time.Hour)))
+}
+
+func TestState_FirstDelivery(t *testing.T) {
+	s := New()
+	s.SetPeers([]string{"n1", "n2"})
+
+	assert.True(t, s.FirstBatch("n1", 1))
+	assert.False(t, s.FirstBatch("n1", 1), "retransmission")
+	assert.True(t, s.FirstBatch("n2", 1), "IDs are per sender")
+	assert.False(t, s.FirstBatch("n1", 0))
+
+	assert.True(t, s.FirstEntry("n1", "n0.1", 2))
+	assert.False(t, s.FirstEntry("n1", "n0.1", 1))
+	assert.False(t, s.FirstEntry("n1", "n0.1", 2))
+	assert.True(t, s.FirstEntry("n2", "n0.1", 2))
+	assert.True(t, s.FirstEntry("n1", "n0.1", 3))
+
+	s.SetPeers([]string{"n2"})
+	s.SetPeers([]string{"n1", "n2"})
+	assert.True(t, s.FirstBatch("n1", 1), "a rejoining neighbour starts afresh")
+}
@@ -0,0 +1,43 @@
+package protocol
+
+// RangeDigest summarizes the broadcast values in one digest tree node,
+// [Lo, Lo+span) where span depends on the node's level: how many there are
+// and the XOR of their hashes.
+type RangeDigest struct {
+	Lo    int    `json:"lo"`
+	Count int    `json:"count"`
+	Hash  uint64 `json:"hash"`
+}
+
+// DigestReq starts or continues an anti-entropy exchange.
+// Root and Count summarize the sender's whole message set; Width is the
+// bucket width both sides must use. Expand, when set, lists the tree nodes
+// one level above Level (by lower bound) whose children at Level the sender
+// wants summarized. Want, when set, lists the buckets whose values the
+// sender is asking for. Have, sent when an exchange starts, is the sender's
+// version vector, which keeps it current where no deltas flow.
+type DigestReq struct {
+	Type   string            `json:"type" validate:"eq=digest"` // "digest"
+	Root   uint64            `json:"root"`
+	Count  int               `json:"count" validate:"min=0"`
+	Width  int               `json:"width" validate:"required,min=1"`
+	Level  int               `json:"level,omitempty" validate:"min=0"`
+	Expand []int             `json:"expand,omitempty"`
+	Want   []int             `json:"want,omitempty"`
+	Have   map[string]uint64 `json:"have,omitempty"`
+}
+
+// DigestOK answers a digest.
+// InSync is set when the roots matched; otherwise Ranges carries the
+// receiver's tree nodes at Level, only those under the Within nodes when
+// set, built with bucket width Width so the requester can diff the same
+// tree. Values answers a request with Want set.
+type DigestOK struct {
+	Type   string        `json:"type" validate:"eq=digest_ok"` // "digest_ok"
+	InSync bool          `json:"in_sync,omitempty"`
+	Width  int           `json:"width,omitempty" validate:"min=0"`
+	Level  int           `json:"level,omitempty" validate:"min=0"`
+	Within []int         `json:"within,omitempty"`
+	Ranges []RangeDigest `json:"ranges,omitempty"`
+	Values []int         `json:"values,omitempty"`
+}
@@ -0,0 +1,81 @@
+package protocol
+
+import "fmt"
+
+// ErrorCode is one of Maelstrom's standard error codes.
+// Codes below 1000 are reserved by Maelstrom; Timeout and Crash are
+// indefinite (the operation may have happened), the rest are definite.
+type ErrorCode int
+
+const (
+	Timeout                ErrorCode = 0
+	NotSupported           ErrorCode = 10
+	TemporarilyUnavailable ErrorCode = 11
+	MalformedRequest       ErrorCode = 12
+	Crash                  ErrorCode = 13
+	Abort                  ErrorCode = 14
+	KeyDoesNotExist        ErrorCode = 20
+	KeyAlreadyExists       ErrorCode = 21
+	PreconditionFailed     ErrorCode = 22
+	TxnConflict            ErrorCode = 30
+)
+
+// String returns the code's name as Maelstrom documents it.
+func (c ErrorCode) String() string {
+	switch c {
+	case Timeout:
+		return "timeout"
+	case NotSupported:
+		return "not-supported"
+	case TemporarilyUnavailable:
+		return "temporarily-unavailable"
+	case MalformedRequest:
+		return "malformed-request"
+	case Crash:
+		return "crash"
+	case Abort:
+		return "abort"
+	case KeyDoesNotExist:
+		return "key-does-not-exist"
+	case KeyAlreadyExists:
+		return "key-already-exists"
+	case PreconditionFailed:
+		return "precondition-failed"
+	case TxnConflict:
+		return "txn-conflict"
+	default:
+		return fmt.Sprintf("error-%d", int(c))
+	}
+}
+
+// Definite reports whether an error with this code means the operation
+// certainly did not take place.
+func (c ErrorCode) Definite() bool {
+	return c != Timeout && c != Crash
+}
+
+// ErrorReply is Maelstrom's error message body. It is also a Go error, so
+// handlers can return one and have it sent back as the reply.
+type ErrorReply struct {
+	Type string    `json:"type" validate:"eq=error"` // "error"
+	Code ErrorCode `json:"code"`
+	Text string    `json:"text,omitempty"`
+}
+
+// NewError creates an error reply with the given code and text.
+func NewError(code ErrorCode, text string) *ErrorReply {
+	return &ErrorReply{Type: "error", Code: code, Text: text}
+}
+
+// Errorf creates an error reply with formatted text.
+func Errorf(code ErrorCode, format string, args ...any) *ErrorReply {
+	return NewError(code, fmt.Sprintf(format, args...))
+}
+
+// Error implements error.
+func (e *ErrorReply) Error() string {
+	if e.Text == "" {
+		return e.Code.String()
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Text)
+}
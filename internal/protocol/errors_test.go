@@ -0,0 +1,39 @@
+package protocol
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorReply_JSON(t *testing.T) {
+	b, err := json.Marshal(Errorf(KeyDoesNotExist, "key %d", 3))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"type":"error","code":20,"text":"key 3"}`, string(b))
+}
+
+func TestErrorReply_IsAnError(t *testing.T) {
+	var err error = fmt.Errorf("wrapped: %w", NewError(TxnConflict, "retry"))
+
+	var reply *ErrorReply
+	require.True(t, errors.As(err, &reply))
+	assert.Equal(t, TxnConflict, reply.Code)
+	assert.Equal(t, "wrapped: txn-conflict: retry", err.Error())
+}
+
+func TestErrorCode_String(t *testing.T) {
+	assert.Equal(t, "timeout", Timeout.String())
+	assert.Equal(t, "temporarily-unavailable", TemporarilyUnavailable.String())
+	assert.Equal(t, "error-1001", ErrorCode(1001).String())
+}
+
+func TestErrorCode_Definite(t *testing.T) {
+	assert.False(t, Timeout.Definite())
+	assert.False(t, Crash.Definite())
+	assert.True(t, Abort.Definite())
+	assert.True(t, PreconditionFailed.Definite())
+}
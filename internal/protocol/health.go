@@ -0,0 +1,25 @@
+package protocol
+
+// ProbeReq represents a liveness probe sent by the failure detector.
+// Origin is set when the probe is sent on behalf of another node, so the
+// reply can be relayed back to it; empty for direct probes.
+type ProbeReq struct {
+	Type   string `json:"type" validate:"eq=probe"` // "probe"
+	Origin string `json:"origin,omitempty"`
+}
+
+// ProbeOK represents a reply to a probe.
+// Target names the node whose liveness is being confirmed; Origin is
+// carried through from the probe so a relaying node knows where to forward.
+type ProbeOK struct {
+	Type   string `json:"type" validate:"eq=probe_ok"` // "probe_ok"
+	Target string `json:"target" validate:"required"`
+	Origin string `json:"origin,omitempty"`
+}
+
+// IndirectProbeReq asks the receiver to probe Target on the sender's behalf.
+// Used once a peer is suspected, to tell a dead peer apart from a bad link.
+type IndirectProbeReq struct {
+	Type   string `json:"type" validate:"eq=probe_req"` // "probe_req"
+	Target string `json:"target" validate:"required"`
+}
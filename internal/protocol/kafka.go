@@ -0,0 +1,61 @@
+package protocol
+
+// SendReq represents a request to append a message to a keyed log.
+// Part of the Kafka-style log workload; the reply carries the offset
+// the message was assigned within that key's log.
+type SendReq struct {
+	Type  string `json:"type" validate:"eq=send"` // "send"
+	MsgID int    `json:"msg_id"`
+	Key   string `json:"key" validate:"required,min=1"`
+	Msg   int    `json:"msg" validate:"required"`
+}
+
+// SendOK represents acknowledgment of an appended log message.
+// Offsets are monotonic and gap-free per key across the whole cluster.
+type SendOK struct {
+	Type   string `json:"type" validate:"eq=send_ok"` // "send_ok"
+	Offset int    `json:"offset"`
+}
+
+// PollReq represents a request to read messages from one or more logs.
+// Offsets maps each key to the first offset the client wants back.
+type PollReq struct {
+	Type    string         `json:"type" validate:"eq=poll"` // "poll"
+	MsgID   int            `json:"msg_id"`
+	Offsets map[string]int `json:"offsets" validate:"required"`
+}
+
+// PollOK represents the messages returned for a poll.
+// Msgs maps each key to [offset, message] pairs in ascending offset order.
+type PollOK struct {
+	Type string              `json:"type" validate:"eq=poll_ok"` // "poll_ok"
+	Msgs map[string][][2]int `json:"msgs"`
+}
+
+// CommitOffsetsReq represents a consumer committing its progress.
+// Offsets maps each key to the highest offset the consumer has processed.
+type CommitOffsetsReq struct {
+	Type    string         `json:"type" validate:"eq=commit_offsets"` // "commit_offsets"
+	MsgID   int            `json:"msg_id"`
+	Offsets map[string]int `json:"offsets" validate:"required"`
+}
+
+// CommitOffsetsOK represents acknowledgment of committed offsets.
+type CommitOffsetsOK struct {
+	Type string `json:"type" validate:"eq=commit_offsets_ok"` // "commit_offsets_ok"
+}
+
+// ListCommittedOffsetsReq represents a request for the committed offsets
+// of the given keys.
+type ListCommittedOffsetsReq struct {
+	Type  string   `json:"type" validate:"eq=list_committed_offsets"` // "list_committed_offsets"
+	MsgID int      `json:"msg_id"`
+	Keys  []string `json:"keys" validate:"required"`
+}
+
+// ListCommittedOffsetsOK represents the committed offsets for the requested
+// keys. Keys that were never committed are omitted.
+type ListCommittedOffsetsOK struct {
+	Type    string         `json:"type" validate:"eq=list_committed_offsets_ok"` // "list_committed_offsets_ok"
+	Offsets map[string]int `json:"offsets"`
+}
@@ -0,0 +1,45 @@
+package protocol
+
+import "encoding/json"
+
+// KVReadReq represents a read request against a Maelstrom KV service
+// (seq-kv, lin-kv or lww-kv). Sent by nodes, never by Maelstrom clients.
+type KVReadReq struct {
+	Type string `json:"type" validate:"eq=read"` // "read"
+	Key  string `json:"key"`
+}
+
+// KVReadOK represents the KV service's reply to a read.
+// Value is kept raw so callers can decode it into whatever type they stored.
+type KVReadOK struct {
+	Type  string          `json:"type" validate:"eq=read_ok"` // "read_ok"
+	Value json.RawMessage `json:"value"`
+}
+
+// KVWriteReq represents an unconditional write to a Maelstrom KV service.
+type KVWriteReq struct {
+	Type  string `json:"type" validate:"eq=write"` // "write"
+	Key   string `json:"key"`
+	Value any    `json:"value"`
+}
+
+// KVWriteOK represents the KV service's acknowledgment of a write.
+type KVWriteOK struct {
+	Type string `json:"type" validate:"eq=write_ok"` // "write_ok"
+}
+
+// KVCasReq represents a compare-and-swap against a Maelstrom KV service.
+// The write of To only happens if the current value equals From; with
+// CreateIfNotExists a missing key is treated as matching and created.
+type KVCasReq struct {
+	Type              string `json:"type" validate:"eq=cas"` // "cas"
+	Key               string `json:"key"`
+	From              any    `json:"from"`
+	To                any    `json:"to"`
+	CreateIfNotExists bool   `json:"create_if_not_exists,omitempty"`
+}
+
+// KVCasOK represents the KV service's acknowledgment of a successful swap.
+type KVCasOK struct {
+	Type string `json:"type" validate:"eq=cas_ok"` // "cas_ok"
+}
@@ -0,0 +1,23 @@
+package protocol
+
+// IHaveReq lazily announces broadcast values the sender has, so a receiver
+// that never gets them through the tree knows whom to graft. Have is the
+// sender's version vector, which keeps it current on a lazy link.
+type IHaveReq struct {
+	Type     string            `json:"type" validate:"eq=ihave"` // "ihave"
+	Messages []int             `json:"messages" validate:"required,min=1"`
+	Have     map[string]uint64 `json:"have,omitempty"`
+}
+
+// GraftReq asks the receiver to push eagerly to the sender again, repairing
+// the broadcast tree, and to send the listed values it announced.
+type GraftReq struct {
+	Type     string `json:"type" validate:"eq=graft"` // "graft"
+	Messages []int  `json:"messages" validate:"required"`
+}
+
+// PruneReq tells the receiver that its eager pushes to the sender are
+// redundant, so the link should only carry announcements from now on.
+type PruneReq struct {
+	Type string `json:"type" validate:"eq=prune"` // "prune"
+}
@@ -0,0 +1,25 @@
+package protocol
+
+// PushReq is one push-pull gossip exchange: Messages carries the sender's
+// hot rumors, which may be empty.
+type PushReq struct {
+	Type     string `json:"type" validate:"eq=push"` // "push"
+	Messages []int  `json:"messages" validate:"required"`
+}
+
+// PushOK answers a push. Known lists the pushed values the receiver already
+// had, which is the feedback that retires rumors at the sender; Messages
+// carries the receiver's own hot rumors back, the pull half of the exchange.
+type PushOK struct {
+	Type     string `json:"type" validate:"eq=push_ok"` // "push_ok"
+	Known    []int  `json:"known,omitempty"`
+	Messages []int  `json:"messages,omitempty"`
+}
+
+// PullOK answers the pull half of a push exchange. Known lists the values in
+// PushOK.Messages the pusher already had, retiring them at the peer that
+// sent them just as PushOK.Known does for pushed values.
+type PullOK struct {
+	Type  string `json:"type" validate:"eq=pull_ok"` // "pull_ok"
+	Known []int  `json:"known" validate:"required"`
+}
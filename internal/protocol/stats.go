@@ -0,0 +1,35 @@
+package protocol
+
+// StatsReq asks a node for a snapshot of its gossip metrics.
+// Not part of any Maelstrom workload; sent by hand or by tests to see the
+// cost of a configuration change without a full Jepsen run.
+type StatsReq struct {
+	Type  string `json:"type" validate:"eq=stats"` // "stats"
+	MsgID int    `json:"msg_id"`
+}
+
+// BatchStats summarizes the sizes of delta batches drained for peers.
+type BatchStats struct {
+	Count    uint64 `json:"count"`
+	Messages uint64 `json:"messages"`
+	Max      int    `json:"max"`
+}
+
+// StatsOK carries a node's metrics snapshot.
+// Sent and Received count messages by body type. PeerMessages counts messages
+// sent to other nodes, and MsgsPerOp divides it by ClientOps, the number of
+// client workload requests served (not init or topology), mirroring
+// Maelstrom's messages-per-operation. FirstSeen maps each of the most recent
+// broadcast values to when this node first saw it, in Unix milliseconds, so
+// latency can be compared across nodes.
+type StatsOK struct {
+	Type         string            `json:"type" validate:"eq=stats_ok"` // "stats_ok"
+	Sent         map[string]uint64 `json:"sent"`
+	Received     map[string]uint64 `json:"received"`
+	ClientOps    uint64            `json:"client_ops"`
+	PeerMessages uint64            `json:"peer_messages"`
+	MsgsPerOp    float64           `json:"msgs_per_op"`
+	Batches      BatchStats        `json:"batches"`
+	Retransmits  uint64            `json:"retransmits"`
+	FirstSeen    map[int]int64     `json:"first_seen"`
+}
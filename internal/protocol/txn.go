@@ -0,0 +1,67 @@
+package protocol
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// TxnOp represents a single micro-operation within a transaction.
+// On the wire it is a three-element array: ["r", key, null] for a read,
+// ["w", key, value] for a write. Value is nil for reads that found no value.
+type TxnOp struct {
+	Op    string
+	Key   int
+	Value *int
+}
+
+// MarshalJSON encodes the micro-operation as Maelstrom's [op, key, value] array.
+func (o TxnOp) MarshalJSON() ([]byte, error) {
+	return json.Marshal([3]any{o.Op, o.Key, o.Value})
+}
+
+// UnmarshalJSON decodes a micro-operation from Maelstrom's [op, key, value] array.
+func (o *TxnOp) UnmarshalJSON(data []byte) error {
+	var raw [3]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("txn op: %w", err)
+	}
+	if err := json.Unmarshal(raw[0], &o.Op); err != nil {
+		return fmt.Errorf("txn op: %w", err)
+	}
+	if err := json.Unmarshal(raw[1], &o.Key); err != nil {
+		return fmt.Errorf("txn op key: %w", err)
+	}
+	o.Value = nil
+	if raw[2] != nil {
+		if err := json.Unmarshal(raw[2], &o.Value); err != nil {
+			return fmt.Errorf("txn op value: %w", err)
+		}
+	}
+	return nil
+}
+
+// TxnReq represents a transaction request in the txn-rw-register workload.
+// Txn lists micro-operations that must be executed atomically in order.
+type TxnReq struct {
+	Type  string  `json:"type" validate:"eq=txn"` // "txn"
+	MsgID int     `json:"msg_id"`
+	Txn   []TxnOp `json:"txn" validate:"required,min=1"`
+}
+
+// TxnOK represents the result of an executed transaction.
+// Txn echoes the request's micro-operations with read values filled in.
+type TxnOK struct {
+	Type      string  `json:"type" validate:"eq=txn_ok"` // "txn_ok"
+	InReplyTo int     `json:"in_reply_to"`
+	Txn       []TxnOp `json:"txn"`
+}
+
+// TxnWrites represents the final writes of one committed transaction, as
+// replicated between nodes inside delta messages. Clock and Node form a
+// Lamport timestamp that uniquely identifies the transaction and orders
+// conflicting writes identically on every replica.
+type TxnWrites struct {
+	Clock  uint64      `json:"clock"`
+	Node   string      `json:"node"`
+	Writes map[int]int `json:"writes"`
+}
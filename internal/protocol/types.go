@@ -1,22 +1,22 @@
 // Package protocol defines JSON message types for Maelstrom distributed systems testing.
 // Implements request/response pairs for echo, unique ID generation, broadcast,
-// topology management, and gossip delta synchronization protocols.
+// grow-only counters, topology management, and gossip delta synchronization protocols.
 package protocol
 
 // EchoReq represents an echo request message for connectivity testing.
 // Simple ping-pong protocol to verify message routing and basic communication
 // between nodes in the distributed system.
 type EchoReq struct {
-	Type  string `json:"type"` // "echo"
+	Type  string `json:"type" validate:"eq=echo"` // "echo"
 	MsgID int    `json:"msg_id"`
-	Echo  string `json:"echo"`
+	Echo  string `json:"echo" validate:"required"`
 }
 
 // EchoOK represents the response to an echo request.
 // Returns the same echo payload to confirm successful message processing
 // and round-trip communication capability.
 type EchoOK struct {
-	Type      string `json:"type"` // "echo_ok"
+	Type      string `json:"type" validate:"eq=echo_ok"` // "echo_ok"
 	MsgID     int    `json:"msg_id"`
 	InReplyTo int    `json:"in_reply_to"`
 	Echo      string `json:"echo"`
@@ -26,31 +26,31 @@ type EchoOK struct {
 // Used to test distributed unique identifier creation across multiple nodes
 // without coordination or central authority.
 type GenerateReq struct {
-	Type string `json:"type"` // "generate"
+	Type string `json:"type" validate:"eq=generate"` // "generate"
 }
 
 // GenerateOK represents the response containing a globally unique identifier.
-// The ID field contains a string guaranteed to be unique across all nodes
-// in the distributed system, typically combining node ID with local counter.
+// The ID field contains a value guaranteed to be unique across all nodes
+// in the distributed system: a "<node>_<counter>" string or a Snowflake integer.
 type GenerateOK struct {
-	Type string `json:"type"` // "generate_ok"
-	ID   string `json:"id"`
+	Type string `json:"type" validate:"eq=generate_ok"` // "generate_ok"
+	ID   any    `json:"id"`
 }
 
 // BroadcastReq represents a message broadcast request to all nodes.
 // Contains an integer message that should be propagated throughout
 // the distributed system using gossip protocols for eventual consistency.
 type BroadcastReq struct {
-	Type    string `json:"type"` // "broadcast"
+	Type    string `json:"type" validate:"eq=broadcast"` // "broadcast"
 	MsgID   int    `json:"msg_id"`
-	Message int    `json:"message"`
+	Message int    `json:"message" validate:"required"`
 }
 
 // BroadcastOK represents acknowledgment of a broadcast message.
 // Confirms that the node has received and processed the broadcast request,
 // though it doesn't guarantee propagation to other nodes.
 type BroadcastOK struct {
-	Type      string `json:"type"` // "broadcast_ok"
+	Type      string `json:"type" validate:"eq=broadcast_ok"` // "broadcast_ok"
 	InReplyTo int    `json:"in_reply_to"`
 }
 
@@ -58,17 +58,42 @@ type BroadcastOK struct {
 // Used to query the current state of broadcast messages for verification
 // and testing of eventual consistency in the gossip protocol.
 type ReadReq struct {
-	Type string `json:"type"` // "read"
+	Type string `json:"type" validate:"eq=read"` // "read"
 }
 
 // ReadOK represents the response containing all known messages.
 // Messages field contains a slice of all integer messages this node
 // has seen, either directly or through gossip propagation.
 type ReadOK struct {
-	Type     string `json:"type"` // "read_ok"
+	Type     string `json:"type" validate:"eq=read_ok"` // "read_ok"
 	Messages []int  `json:"messages"`
 }
 
+// CounterReadOK represents the response to a read in the g-counter workload.
+// Value holds the cluster-wide counter total as seen by this node, which
+// converges to the sum of all acknowledged adds once gossip settles.
+type CounterReadOK struct {
+	Type  string `json:"type" validate:"eq=read_ok"` // "read_ok"
+	Value int    `json:"value"`
+}
+
+// AddReq represents a request to increment the grow-only counter.
+// Delta is added to the receiving node's own contribution and then
+// propagated to peers through delta gossip.
+type AddReq struct {
+	Type  string `json:"type" validate:"eq=add"` // "add"
+	MsgID int    `json:"msg_id"`
+	Delta int    `json:"delta" validate:"required,min=0"`
+}
+
+// AddOK represents acknowledgment of a counter increment.
+// Confirms the delta was applied locally; other nodes observe it
+// once the next gossip round reaches them.
+type AddOK struct {
+	Type      string `json:"type" validate:"eq=add_ok"` // "add_ok"
+	InReplyTo int    `json:"in_reply_to"`
+}
+
 // Topology represents the network topology as a map of node connections.
 // Maps each node ID to a slice of its directly connected neighbor node IDs,
 // defining the communication graph for gossip message propagation.
@@ -78,28 +103,68 @@ type Topology map[string][]string
 // Sent by Maelstrom to inform nodes about their network neighbors
 // and establish the communication topology for testing scenarios.
 type TopologyReq struct {
-	Type     string   `json:"type"` // "topology"
-	Topology Topology `json:"topology"`
+	Type     string   `json:"type" validate:"eq=topology"` // "topology"
+	Topology Topology `json:"topology" validate:"required"`
 }
 
 // TopologyOK represents acknowledgment of topology configuration.
 // Confirms that the node has received and processed the topology
 // information and is ready for distributed protocol testing.
 type TopologyOK struct {
-	Type string `json:"type"` // "topology_ok"
+	Type string `json:"type" validate:"eq=topology_ok"` // "topology_ok"
 }
 
 // DeltaReq represents a gossip delta synchronization message.
 // Contains a batch of message IDs being shared between peer nodes
 // for efficient propagation and eventual consistency achievement.
+// Counters optionally carries the sender's per-node g-counter contributions,
+// tagged with the sender-local CounterVersion they were taken at.
+// Txns optionally carries replicated transaction write sets; TxnUpto is the
+// sender's replication log index just past the last one included.
+// BatchID identifies the Messages batch per sender/receiver pair and is
+// reused on retransmission; zero means the delta carries no batch.
+// Entries carries origin-tagged broadcast values the receiver was missing
+// according to its last known version vector, and Have is the sender's own
+// vector, so the receiver never sends back what the sender already holds.
+// Skip lists origins whose entries the sender trimmed before the receiver
+// had them; their values go out untagged, so the receiver counts the
+// entries up to Skip as held.
 type DeltaReq struct {
-	Type     string `json:"type"` // "delta"
-	Messages []int  `json:"messages"`
+	Type           string            `json:"type" validate:"eq=delta"` // "delta"
+	BatchID        uint64            `json:"batch_id,omitempty"`
+	Messages       []int             `json:"messages,omitempty" validate:"notnull"`
+	Entries        []Entry           `json:"entries,omitempty" validate:"notnull"`
+	Have           map[string]uint64 `json:"have,omitempty"`
+	Skip           map[string]uint64 `json:"skip,omitempty"`
+	Counters       map[string]int    `json:"counters,omitempty"`
+	CounterVersion uint64            `json:"counter_version,omitempty"`
+	Txns           []TxnWrites       `json:"txns,omitempty"`
+	TxnUpto        int               `json:"txn_upto,omitempty" validate:"min=0"`
+}
+
+// Entry is a broadcast value tagged with the node that first learned it
+// and that node's sequence number for it, starting at 1. A node's version
+// vector maps each origin to the highest sequence number it holds with no
+// gap below.
+type Entry struct {
+	Origin string `json:"origin"`
+	Seq    uint64 `json:"seq"`
+	Value  int    `json:"value"`
 }
 
 // DeltaOK represents acknowledgment of a delta synchronization message.
 // Confirms successful receipt of gossip messages and triggers cleanup
 // of in-flight message tracking for retry logic management.
+// BatchID, CounterVersion and TxnUpto echo the corresponding fields of the
+// delta, so the sender retires exactly what was acknowledged. The batch ID is
+// carried in its own field because Maelstrom routes any reply with a non-zero
+// in_reply_to to an RPC callback rather than the delta_ok handler.
+// Have is the receiver's version vector after applying the delta, which
+// tells the sender where the next entries should start.
 type DeltaOK struct {
-	Type string `json:"type"` // "delta_ok"
-}
\ No newline at end of file
+	Type           string            `json:"type" validate:"eq=delta_ok"` // "delta_ok"
+	BatchID        uint64            `json:"batch_id,omitempty"`
+	Have           map[string]uint64 `json:"have,omitempty"`
+	CounterVersion uint64            `json:"counter_version,omitempty"`
+	TxnUpto        int               `json:"txn_upto,omitempty" validate:"min=0"`
+}
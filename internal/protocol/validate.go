@@ -0,0 +1,187 @@
+package protocol
+
+import (
+	// --- Standard Lib ---
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Validate checks a decoded message against the `validate` tags on its
+// struct fields. body is the raw JSON v was decoded from, needed to tell a
+// missing field from one set to its zero value. Supported rules, comma
+// separated:
+//
+//	required   the field must be present and not null
+//	notnull    the field may be absent but must not be null
+//	eq=X       a string field must equal X; used to pin the type tag
+//	oneof=A B  a string field must be one of the listed values
+//	min=N      numbers must be >= N; strings, slices and maps need len >= N
+//	max=N      as min, for an upper bound
+//
+// Returns a *ErrorReply with code MalformedRequest naming every violation.
+func Validate(body []byte, v any) error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+	rules := rulesFor(rv.Type())
+	if len(rules) == 0 {
+		return nil
+	}
+
+	var raw map[string]json.RawMessage
+	if rulesNeedRaw(rules) {
+		if err := json.Unmarshal(body, &raw); err != nil {
+			return Errorf(MalformedRequest, "body is not an object: %v", err)
+		}
+	}
+
+	var problems []string
+	for _, f := range rules {
+		if p := f.check(rv.Field(f.index), raw); p != "" {
+			problems = append(problems, f.key+" "+p)
+		}
+	}
+	if len(problems) > 0 {
+		return Errorf(MalformedRequest, "%s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// fieldRules are the parsed validate tag of one struct field.
+type fieldRules struct {
+	index    int
+	key      string
+	required bool
+	notNull  bool
+	eq       *string
+	oneOf    []string
+	min, max *float64
+}
+
+// rulesCache maps reflect.Type to []fieldRules, so tags are parsed once per type.
+var rulesCache sync.Map
+
+// rulesFor returns the parsed rules for struct type t.
+func rulesFor(t reflect.Type) []fieldRules {
+	if cached, ok := rulesCache.Load(t); ok {
+		return cached.([]fieldRules)
+	}
+
+	var rules []fieldRules
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag, ok := sf.Tag.Lookup("validate")
+		if !ok {
+			continue
+		}
+		key, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		if key == "" {
+			key = sf.Name
+		}
+		rules = append(rules, parseRules(i, key, tag))
+	}
+	rulesCache.Store(t, rules)
+	return rules
+}
+
+// parseRules parses one validate tag. Malformed tags are programming
+// errors in this package, so they panic rather than fail every request.
+func parseRules(index int, key, tag string) fieldRules {
+	f := fieldRules{index: index, key: key}
+	for _, rule := range strings.Split(tag, ",") {
+		name, arg, _ := strings.Cut(rule, "=")
+		switch name {
+		case "required":
+			f.required = true
+		case "notnull":
+			f.notNull = true
+		case "eq":
+			f.eq = &arg
+		case "oneof":
+			f.oneOf = strings.Fields(arg)
+		case "min", "max":
+			n, err := strconv.ParseFloat(arg, 64)
+			if err != nil {
+				panic(fmt.Sprintf("protocol: bad %s in validate tag of %s: %v", name, key, err))
+			}
+			if name == "min" {
+				f.min = &n
+			} else {
+				f.max = &n
+			}
+		default:
+			panic(fmt.Sprintf("protocol: unknown validate rule %q on %s", rule, key))
+		}
+	}
+	return f
+}
+
+// rulesNeedRaw reports whether any rule needs to see the raw JSON keys.
+func rulesNeedRaw(rules []fieldRules) bool {
+	for _, f := range rules {
+		if f.required || f.notNull {
+			return true
+		}
+	}
+	return false
+}
+
+// check applies f to the decoded field value, returning a description of
+// the first violation or "" if there is none.
+func (f fieldRules) check(v reflect.Value, raw map[string]json.RawMessage) string {
+	if f.required || f.notNull {
+		r, present := raw[f.key]
+		isNull := present && strings.TrimSpace(string(r)) == "null"
+		switch {
+		case f.required && !present:
+			return "is required"
+		case isNull:
+			return "must not be null"
+		}
+	}
+
+	if f.eq != nil && v.String() != *f.eq {
+		return fmt.Sprintf("must be %q, got %q", *f.eq, v.String())
+	}
+	if f.oneOf != nil && !slices.Contains(f.oneOf, v.String()) {
+		return fmt.Sprintf("must be one of %v, got %q", f.oneOf, v.String())
+	}
+
+	if f.min == nil && f.max == nil {
+		return ""
+	}
+	n, what := measure(v)
+	if f.min != nil && n < *f.min {
+		return fmt.Sprintf("%s must be at least %v", what, *f.min)
+	}
+	if f.max != nil && n > *f.max {
+		return fmt.Sprintf("%s must be at most %v", what, *f.max)
+	}
+	return ""
+}
+
+// measure returns the quantity min and max constrain: the value of a
+// number, the length of anything else.
+func measure(v reflect.Value) (float64, string) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), "value"
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), "value"
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), "value"
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return float64(v.Len()), "length"
+	default:
+		return 0, "value"
+	}
+}
@@ -0,0 +1,229 @@
+package protocol
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// decodeAndValidate decodes body into a new T and validates it, as the
+// gossip handlers do.
+func decodeAndValidate[T any](body string) error {
+	var v T
+	if err := json.Unmarshal([]byte(body), &v); err != nil {
+		return err
+	}
+	return Validate([]byte(body), &v)
+}
+
+type validateCase struct {
+	name  string
+	body  string
+	valid bool
+}
+
+// runCases checks every case against message type T.
+func runCases[T any](t *testing.T, cases []validateCase) {
+	t.Helper()
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := decodeAndValidate[T](c.body)
+			if c.valid {
+				assert.NoError(t, err)
+				return
+			}
+			var reply *ErrorReply
+			require.True(t, errors.As(err, &reply), "want an error reply, got %v", err)
+			assert.Equal(t, MalformedRequest, reply.Code)
+		})
+	}
+}
+
+func TestValidate_Echo(t *testing.T) {
+	runCases[EchoReq](t, []validateCase{
+		{"ok", `{"type":"echo","echo":"hi"}`, true},
+		{"empty echo is present", `{"type":"echo","echo":""}`, true},
+		{"missing echo", `{"type":"echo"}`, false},
+		{"wrong type", `{"type":"echo_ok","echo":"hi"}`, false},
+	})
+}
+
+func TestValidate_Generate(t *testing.T) {
+	runCases[GenerateReq](t, []validateCase{
+		{"ok", `{"type":"generate"}`, true},
+		{"missing type", `{}`, false},
+	})
+}
+
+func TestValidate_Broadcast(t *testing.T) {
+	runCases[BroadcastReq](t, []validateCase{
+		{"ok", `{"type":"broadcast","message":7}`, true},
+		{"zero message", `{"type":"broadcast","message":0}`, true},
+		{"missing message", `{"type":"broadcast"}`, false},
+		{"null message", `{"type":"broadcast","message":null}`, false},
+	})
+}
+
+func TestValidate_Read(t *testing.T) {
+	runCases[ReadReq](t, []validateCase{
+		{"ok", `{"type":"read"}`, true},
+		{"wrong type", `{"type":"reed"}`, false},
+	})
+}
+
+func TestValidate_Add(t *testing.T) {
+	runCases[AddReq](t, []validateCase{
+		{"ok", `{"type":"add","delta":3}`, true},
+		{"zero delta", `{"type":"add","delta":0}`, true},
+		{"negative delta", `{"type":"add","delta":-1}`, false},
+		{"missing delta", `{"type":"add"}`, false},
+	})
+}
+
+func TestValidate_Topology(t *testing.T) {
+	runCases[TopologyReq](t, []validateCase{
+		{"ok", `{"type":"topology","topology":{"n0":["n1"]}}`, true},
+		{"missing topology", `{"type":"topology"}`, false},
+		{"null topology", `{"type":"topology","topology":null}`, false},
+	})
+}
+
+func TestValidate_Delta(t *testing.T) {
+	runCases[DeltaReq](t, []validateCase{
+		{"ok", `{"type":"delta","batch_id":1,"messages":[1,2]}`, true},
+		{"counters only", `{"type":"delta","counters":{"n0":1},"counter_version":1}`, true},
+		{"null messages", `{"type":"delta","messages":null}`, false},
+		{"entries", `{"type":"delta","entries":[{"origin":"n1.0","seq":1,"value":7}],"have":{"n0.0":3}}`, true},
+		{"null entries", `{"type":"delta","entries":null}`, false},
+		{"negative txn_upto", `{"type":"delta","txn_upto":-1}`, false},
+	})
+	runCases[DeltaOK](t, []validateCase{
+		{"ok", `{"type":"delta_ok","batch_id":1}`, true},
+		{"wrong type", `{"type":"delta","batch_id":1}`, false},
+	})
+}
+
+func TestValidate_Kafka(t *testing.T) {
+	runCases[SendReq](t, []validateCase{
+		{"ok", `{"type":"send","key":"k1","msg":0}`, true},
+		{"empty key", `{"type":"send","key":"","msg":1}`, false},
+		{"missing msg", `{"type":"send","key":"k1"}`, false},
+	})
+	runCases[PollReq](t, []validateCase{
+		{"ok", `{"type":"poll","offsets":{"k1":0}}`, true},
+		{"missing offsets", `{"type":"poll"}`, false},
+	})
+	runCases[CommitOffsetsReq](t, []validateCase{
+		{"ok", `{"type":"commit_offsets","offsets":{}}`, true},
+		{"null offsets", `{"type":"commit_offsets","offsets":null}`, false},
+	})
+	runCases[ListCommittedOffsetsReq](t, []validateCase{
+		{"ok", `{"type":"list_committed_offsets","keys":["k1"]}`, true},
+		{"missing keys", `{"type":"list_committed_offsets"}`, false},
+	})
+}
+
+func TestValidate_Txn(t *testing.T) {
+	runCases[TxnReq](t, []validateCase{
+		{"ok", `{"type":"txn","txn":[["r",1,null]]}`, true},
+		{"empty txn", `{"type":"txn","txn":[]}`, false},
+		{"missing txn", `{"type":"txn"}`, false},
+	})
+}
+
+func TestValidate_Health(t *testing.T) {
+	runCases[ProbeReq](t, []validateCase{
+		{"ok", `{"type":"probe"}`, true},
+		{"relayed", `{"type":"probe","origin":"n1"}`, true},
+	})
+	runCases[ProbeOK](t, []validateCase{
+		{"ok", `{"type":"probe_ok","target":"n1"}`, true},
+		{"missing target", `{"type":"probe_ok"}`, false},
+	})
+	runCases[IndirectProbeReq](t, []validateCase{
+		{"ok", `{"type":"probe_req","target":"n2"}`, true},
+		{"missing target", `{"type":"probe_req"}`, false},
+	})
+}
+
+func TestValidate_Digest(t *testing.T) {
+	runCases[DigestReq](t, []validateCase{
+		{"ok", `{"type":"digest","root":1,"count":2,"width":64}`, true},
+		{"missing width", `{"type":"digest","root":1,"count":2}`, false},
+		{"zero width", `{"type":"digest","width":0}`, false},
+		{"negative count", `{"type":"digest","count":-1,"width":64}`, false},
+	})
+	runCases[DigestOK](t, []validateCase{
+		{"ok", `{"type":"digest_ok","in_sync":true}`, true},
+	})
+}
+
+func TestValidate_Push(t *testing.T) {
+	runCases[PushReq](t, []validateCase{
+		{"ok", `{"type":"push","messages":[1,2]}`, true},
+		{"empty", `{"type":"push","messages":[]}`, true},
+		{"missing messages", `{"type":"push"}`, false},
+	})
+	runCases[PushOK](t, []validateCase{
+		{"ok", `{"type":"push_ok","known":[1],"messages":[3]}`, true},
+		{"wrong type", `{"type":"push"}`, false},
+	})
+	runCases[PullOK](t, []validateCase{
+		{"ok", `{"type":"pull_ok","known":[3]}`, true},
+		{"missing known", `{"type":"pull_ok"}`, false},
+	})
+}
+
+func TestValidate_Plumtree(t *testing.T) {
+	runCases[IHaveReq](t, []validateCase{
+		{"ok", `{"type":"ihave","messages":[1,2]}`, true},
+		{"empty", `{"type":"ihave","messages":[]}`, false},
+		{"missing messages", `{"type":"ihave"}`, false},
+	})
+	runCases[GraftReq](t, []validateCase{
+		{"ok", `{"type":"graft","messages":[1]}`, true},
+		{"empty", `{"type":"graft","messages":[]}`, true},
+		{"missing messages", `{"type":"graft"}`, false},
+	})
+	runCases[PruneReq](t, []validateCase{
+		{"ok", `{"type":"prune"}`, true},
+		{"wrong type", `{"type":"prunes"}`, false},
+	})
+}
+
+func TestValidate_Stats(t *testing.T) {
+	runCases[StatsReq](t, []validateCase{
+		{"ok", `{"type":"stats"}`, true},
+		{"wrong type", `{"type":"stat"}`, false},
+	})
+}
+
+func TestValidate_ReportsEveryProblem(t *testing.T) {
+	err := decodeAndValidate[SendReq](`{"type":"sned","key":""}`)
+
+	var reply *ErrorReply
+	require.True(t, errors.As(err, &reply))
+	assert.Contains(t, reply.Text, "type must be")
+	assert.Contains(t, reply.Text, "key length must be at least 1")
+	assert.Contains(t, reply.Text, "msg is required")
+}
+
+func TestValidate_AllTagsParse(t *testing.T) {
+	types := []any{
+		EchoReq{}, EchoOK{}, GenerateReq{}, GenerateOK{}, BroadcastReq{}, BroadcastOK{},
+		ReadReq{}, ReadOK{}, CounterReadOK{}, AddReq{}, AddOK{}, TopologyReq{}, TopologyOK{},
+		DeltaReq{}, DeltaOK{}, KVReadReq{}, KVReadOK{}, KVWriteReq{}, KVWriteOK{}, KVCasReq{}, KVCasOK{},
+		SendReq{}, SendOK{}, PollReq{}, PollOK{}, CommitOffsetsReq{}, CommitOffsetsOK{},
+		ListCommittedOffsetsReq{}, ListCommittedOffsetsOK{}, TxnReq{}, TxnOK{},
+		ProbeReq{}, ProbeOK{}, IndirectProbeReq{}, DigestReq{}, DigestOK{}, PushReq{}, PushOK{}, PullOK{}, IHaveReq{}, GraftReq{}, PruneReq{}, StatsReq{}, StatsOK{}, ErrorReply{},
+	}
+	for _, v := range types {
+		typ := reflect.TypeOf(v)
+		assert.NotPanics(t, func() { rulesFor(typ) }, typ.Name())
+		assert.NotEmpty(t, rulesFor(typ), "%s has no validate tags", typ.Name())
+	}
+}
@@ -0,0 +1,90 @@
+package queue
+
+import "sync"
+
+// GCounter implements a thread-safe grow-only counter CRDT.
+// Each node only ever increments its own slot; replicas converge by taking
+// the per-node maximum, so the cluster-wide value is the sum of all slots.
+type GCounter struct {
+	mu sync.RWMutex
+
+	// values maps node IDs to that node's total contribution
+	values map[string]int
+
+	// version increases on every local change so peers can tell whether
+	// they have acknowledged the latest state
+	version uint64
+}
+
+// NewGCounter creates an empty grow-only counter.
+func NewGCounter() *GCounter {
+	return &GCounter{
+		values: make(map[string]int),
+	}
+}
+
+// Add increments the contribution of the given node by delta.
+// Negative deltas are ignored since the counter may only grow.
+func (c *GCounter) Add(node string, delta int) {
+	if delta <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.values[node] += delta
+	c.version++
+}
+
+// Merge folds a remote snapshot into the counter by taking the maximum
+// contribution for each node. Returns true if any slot changed.
+func (c *GCounter) Merge(other map[string]int) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	changed := false
+	for node, v := range other {
+		if v > c.values[node] {
+			c.values[node] = v
+			changed = true
+		}
+	}
+	if changed {
+		c.version++
+	}
+	return changed
+}
+
+// Value returns the cluster-wide total known to this replica.
+func (c *GCounter) Value() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	total := 0
+	for _, v := range c.values {
+		total += v
+	}
+	return total
+}
+
+// Version returns the current local version of the counter state.
+func (c *GCounter) Version() uint64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.version
+}
+
+// Snapshot returns a copy of the per-node contributions together with
+// the version they correspond to.
+func (c *GCounter) Snapshot() (map[string]int, uint64) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	out := make(map[string]int, len(c.values))
+	for node, v := range c.values {
+		out[node] = v
+	}
+	return out, c.version
+}
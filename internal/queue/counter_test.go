@@ -0,0 +1,85 @@
+package queue
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGCounter_Add(t *testing.T) {
+	c := NewGCounter()
+	assert.Equal(t, 0, c.Value())
+
+	c.Add("n0", 3)
+	c.Add("n0", 2)
+	c.Add("n1", 1)
+	assert.Equal(t, 6, c.Value())
+	assert.Equal(t, uint64(3), c.Version())
+}
+
+func TestGCounter_AddIgnoresNonPositive(t *testing.T) {
+	c := NewGCounter()
+	c.Add("n0", 0)
+	c.Add("n0", -5)
+
+	assert.Equal(t, 0, c.Value())
+	assert.Equal(t, uint64(0), c.Version())
+}
+
+func TestGCounter_Merge(t *testing.T) {
+	c := NewGCounter()
+	c.Add("n0", 5)
+
+	// Stale remote view of n0 must not lower the local slot
+	changed := c.Merge(map[string]int{"n0": 2, "n1": 4})
+	assert.True(t, changed)
+	assert.Equal(t, 9, c.Value())
+
+	// Merging the same state again is a no-op
+	v := c.Version()
+	changed = c.Merge(map[string]int{"n0": 2, "n1": 4})
+	assert.False(t, changed)
+	assert.Equal(t, v, c.Version())
+}
+
+func TestGCounter_SnapshotIsCopy(t *testing.T) {
+	c := NewGCounter()
+	c.Add("n0", 1)
+
+	snap, version := c.Snapshot()
+	snap["n0"] = 100
+
+	assert.Equal(t, 1, c.Value())
+	assert.Equal(t, uint64(1), version)
+}
+
+func TestGCounter_Converges(t *testing.T) {
+	a, b := NewGCounter(), NewGCounter()
+	a.Add("n0", 3)
+	b.Add("n1", 7)
+
+	snapA, _ := a.Snapshot()
+	snapB, _ := b.Snapshot()
+	a.Merge(snapB)
+	b.Merge(snapA)
+
+	assert.Equal(t, 10, a.Value())
+	assert.Equal(t, a.Value(), b.Value())
+}
+
+func TestGCounter_ConcurrentAdd(t *testing.T) {
+	c := NewGCounter()
+	var wg sync.WaitGroup
+
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.Add("n0", 1)
+		}()
+	}
+
+	wg.Wait()
+	assert.Equal(t, 100, c.Value())
+}
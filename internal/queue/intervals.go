@@ -0,0 +1,286 @@
+package queue
+
+import (
+	// --- Standard Lib ---
+	"iter"
+	"sort"
+	"sync"
+)
+
+// run is a closed range [lo, hi] of consecutive integers.
+type run struct {
+	lo, hi int
+}
+
+// intervalSet implements IntSet as a sorted list of disjoint, non-adjacent
+// runs. Broadcast values tend to arrive as long consecutive sequences, so a
+// run list stays a handful of entries where a map would hold every value,
+// and GetSlice comes back sorted for free.
+type intervalSet struct {
+	// mu guards runs and count
+	mu sync.RWMutex
+
+	// runs holds the set's members in ascending, non-overlapping order;
+	// adjacent runs are always merged
+	runs []run
+
+	// count caches the number of members across all runs
+	count int
+}
+
+// newIntervalSet creates an empty run-length encoded integer set.
+func newIntervalSet() *intervalSet {
+	return &intervalSet{}
+}
+
+// search returns the index of the first run whose upper bound is >= v.
+// Caller must hold s.mu.
+func (s *intervalSet) search(v int) int {
+	return sort.Search(len(s.runs), func(i int) bool { return s.runs[i].hi >= v })
+}
+
+// Has checks if the given integer exists in the set.
+func (s *intervalSet) Has(v int) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	i := s.search(v)
+	return i < len(s.runs) && s.runs[i].lo <= v
+}
+
+// Add inserts an integer, extending or joining neighbouring runs where it
+// touches them. Returns true if the value was newly added.
+func (s *intervalSet) Add(v int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.search(v)
+	if i < len(s.runs) && s.runs[i].lo <= v {
+		return false
+	}
+	s.count++
+
+	joinsLeft := i > 0 && s.runs[i-1].hi+1 == v
+	joinsRight := i < len(s.runs) && s.runs[i].lo-1 == v
+	switch {
+	case joinsLeft && joinsRight:
+		s.runs[i-1].hi = s.runs[i].hi
+		s.runs = append(s.runs[:i], s.runs[i+1:]...)
+	case joinsLeft:
+		s.runs[i-1].hi = v
+	case joinsRight:
+		s.runs[i].lo = v
+	default:
+		s.runs = append(s.runs, run{})
+		copy(s.runs[i+1:], s.runs[i:])
+		s.runs[i] = run{lo: v, hi: v}
+	}
+	return true
+}
+
+// AddAll inserts every given integer. Returns how many were new.
+func (s *intervalSet) AddAll(vs ...int) int {
+	added := 0
+	for _, v := range vs {
+		if s.Add(v) {
+			added++
+		}
+	}
+	return added
+}
+
+// RemoveAll deletes every given integer, trimming or splitting the runs
+// that held them. Returns how many were present.
+func (s *intervalSet) RemoveAll(vs ...int) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	removed := 0
+	for _, v := range vs {
+		i := s.search(v)
+		if i == len(s.runs) || s.runs[i].lo > v {
+			continue
+		}
+		removed++
+		s.count--
+
+		r := s.runs[i]
+		switch {
+		case r.lo == r.hi:
+			s.runs = append(s.runs[:i], s.runs[i+1:]...)
+		case v == r.lo:
+			s.runs[i].lo++
+		case v == r.hi:
+			s.runs[i].hi--
+		default:
+			s.runs = append(s.runs, run{})
+			copy(s.runs[i+1:], s.runs[i:])
+			s.runs[i] = run{lo: r.lo, hi: v - 1}
+			s.runs[i+1] = run{lo: v + 1, hi: r.hi}
+		}
+	}
+	return removed
+}
+
+// Len returns the number of integers in the set.
+func (s *intervalSet) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.count
+}
+
+// GetSlice returns all integers in the set in ascending order.
+func (s *intervalSet) GetSlice() []int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]int, 0, s.count)
+	for _, r := range s.runs {
+		for v := r.lo; ; v++ {
+			out = append(out, v)
+			if v == r.hi {
+				break
+			}
+		}
+	}
+	return out
+}
+
+// Snapshot returns all integers in the set in ascending order.
+func (s *intervalSet) Snapshot() []int {
+	return s.GetSlice()
+}
+
+// All iterates over the set in ascending order. It works on a copy of the
+// runs taken when iteration starts, so the set may be modified meanwhile.
+func (s *intervalSet) All() iter.Seq[int] {
+	return func(yield func(int) bool) {
+		for _, r := range s.snapshot() {
+			for v := r.lo; ; v++ {
+				if !yield(v) {
+					return
+				}
+				if v == r.hi {
+					break
+				}
+			}
+		}
+	}
+}
+
+// Union returns a new set holding every member of s or o.
+func (s *intervalSet) Union(o *intervalSet) *intervalSet {
+	a, b := s.snapshot(), o.snapshot()
+	out := newIntervalSet()
+
+	for len(a) > 0 || len(b) > 0 {
+		var next run
+		if len(b) == 0 || (len(a) > 0 && a[0].lo <= b[0].lo) {
+			next, a = a[0], a[1:]
+		} else {
+			next, b = b[0], b[1:]
+		}
+		out.appendRun(next)
+	}
+	return out
+}
+
+// Difference returns a new set holding the members of s that are not in o.
+func (s *intervalSet) Difference(o *intervalSet) *intervalSet {
+	a, b := s.snapshot(), o.snapshot()
+	out := newIntervalSet()
+
+	for _, r := range a {
+		for len(b) > 0 && b[0].hi < r.lo {
+			b = b[1:]
+		}
+
+		lo, covered := r.lo, false
+		for _, c := range b {
+			if c.lo > r.hi {
+				break
+			}
+			if c.lo > lo {
+				out.appendRun(run{lo: lo, hi: c.lo - 1})
+			}
+			if c.hi >= r.hi {
+				covered = true
+				break
+			}
+			lo = c.hi + 1
+		}
+		if !covered {
+			out.appendRun(run{lo: lo, hi: r.hi})
+		}
+	}
+	return out
+}
+
+// Diff returns the integers in s that are not in other, in ascending order.
+// Works run by run when other is also an interval set.
+func (s *intervalSet) Diff(other IntSet) []int {
+	if o, ok := other.(*intervalSet); ok {
+		return s.Difference(o).GetSlice()
+	}
+	return diff(s.Snapshot(), other)
+}
+
+// DrainN removes and returns up to n integers, smallest first.
+// Returns nil when the set is empty.
+func (s *intervalSet) DrainN(n int) []int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.count == 0 || n <= 0 {
+		return nil
+	}
+
+	out := make([]int, 0, min(n, s.count))
+	for len(out) < n && len(s.runs) > 0 {
+		r := &s.runs[0]
+		out = append(out, r.lo)
+		s.count--
+		if r.lo == r.hi {
+			s.runs = s.runs[1:]
+			continue
+		}
+		r.lo++
+	}
+	return out
+}
+
+// Clear removes all elements from the set.
+func (s *intervalSet) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.runs = nil
+	s.count = 0
+}
+
+// snapshot returns a copy of the runs.
+func (s *intervalSet) snapshot() []run {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]run, len(s.runs))
+	copy(out, s.runs)
+	return out
+}
+
+// appendRun adds r, which must not start before the last run, merging it
+// with the last run if they overlap or touch. Only used while building a
+// set that is not yet shared, so it takes no lock.
+func (s *intervalSet) appendRun(r run) {
+	if n := len(s.runs); n > 0 && (r.lo <= s.runs[n-1].hi || r.lo-1 == s.runs[n-1].hi) {
+		last := &s.runs[n-1]
+		if r.hi > last.hi {
+			s.count += r.hi - last.hi
+			last.hi = r.hi
+		}
+		return
+	}
+	s.runs = append(s.runs, r)
+	s.count += r.hi - r.lo + 1
+}
@@ -0,0 +1,204 @@
+package queue
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func intervalsOf(values ...int) *intervalSet {
+	s := newIntervalSet()
+	for _, v := range values {
+		s.Add(v)
+	}
+	return s
+}
+
+func TestIntervalSet_AddMergesRuns(t *testing.T) {
+	s := newIntervalSet()
+
+	assert.True(t, s.Add(1))
+	assert.True(t, s.Add(3))
+	assert.Len(t, s.runs, 2)
+
+	// 2 joins [1,1] and [3,3]
+	assert.True(t, s.Add(2))
+	assert.Equal(t, []run{{1, 3}}, s.runs)
+
+	assert.False(t, s.Add(2))
+	assert.True(t, s.Add(0))
+	assert.True(t, s.Add(4))
+	assert.Equal(t, []run{{0, 4}}, s.runs)
+	assert.Equal(t, 5, s.Len())
+}
+
+func TestIntervalSet_Has(t *testing.T) {
+	s := intervalsOf(1, 2, 3, 10, -5)
+
+	for _, v := range []int{1, 2, 3, 10, -5} {
+		assert.True(t, s.Has(v), v)
+	}
+	for _, v := range []int{0, 4, 9, 11, -4} {
+		assert.False(t, s.Has(v), v)
+	}
+}
+
+func TestIntervalSet_MatchesMapSet(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	m := newIntSet()
+	s := newIntervalSet()
+
+	for i := 0; i < 5000; i++ {
+		v := rng.Intn(2000) - 1000
+		assert.Equal(t, m.Add(v), s.Add(v))
+	}
+
+	want := m.GetSlice()
+	slices.Sort(want)
+	assert.Equal(t, want, s.GetSlice())
+	assert.Equal(t, len(want), s.Len())
+}
+
+func TestIntervalSet_All(t *testing.T) {
+	s := intervalsOf(5, 1, 2, 9)
+
+	var got []int
+	for v := range s.All() {
+		got = append(got, v)
+	}
+	assert.Equal(t, []int{1, 2, 5, 9}, got)
+
+	got = nil
+	for v := range s.All() {
+		if v > 2 {
+			break
+		}
+		got = append(got, v)
+	}
+	assert.Equal(t, []int{1, 2}, got)
+}
+
+func TestIntervalSet_Union(t *testing.T) {
+	a := intervalsOf(1, 2, 3, 10, 11)
+	b := intervalsOf(4, 5, 11, 12, 20)
+
+	u := a.Union(b)
+	assert.Equal(t, []run{{1, 5}, {10, 12}, {20, 20}}, u.runs)
+	assert.Equal(t, 9, u.Len())
+
+	// Inputs are untouched
+	assert.Equal(t, 5, a.Len())
+	assert.Equal(t, 5, b.Len())
+}
+
+func TestIntervalSet_Difference(t *testing.T) {
+	a := intervalsOf(1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 20, 21)
+	b := intervalsOf(0, 3, 4, 8, 21, 22)
+
+	d := a.Difference(b)
+	assert.Equal(t, []int{1, 2, 5, 6, 7, 9, 10, 20}, d.GetSlice())
+	assert.Equal(t, 8, d.Len())
+
+	assert.Empty(t, b.Difference(b).GetSlice())
+	assert.Equal(t, a.GetSlice(), a.Difference(newIntervalSet()).GetSlice())
+}
+
+func TestIntervalSet_Clear(t *testing.T) {
+	s := intervalsOf(1, 2, 3)
+	s.Clear()
+
+	assert.Equal(t, 0, s.Len())
+	assert.False(t, s.Has(2))
+	assert.Empty(t, s.GetSlice())
+}
+
+func TestNewMessagesQueue_Kind(t *testing.T) {
+	assert.IsType(t, &intSet{}, NewMessagesQueue(MapSet).IntSet)
+	assert.IsType(t, &intervalSet{}, NewMessagesQueue(IntervalSet).IntSet)
+}
+
+// benchSets builds each set kind for benchmarks.
+var benchSets = []struct {
+	name string
+	new  func() IntSet
+}{
+	{"map", func() IntSet { s := newIntSet(); return &s }},
+	{"intervals", func() IntSet { return newIntervalSet() }},
+}
+
+func BenchmarkSet_AddSequential(b *testing.B) {
+	for _, bs := range benchSets {
+		b.Run(bs.name, func(b *testing.B) {
+			s := bs.new()
+			for i := 0; i < b.N; i++ {
+				s.Add(i)
+			}
+		})
+	}
+}
+
+func BenchmarkSet_AddRandom(b *testing.B) {
+	for _, bs := range benchSets {
+		b.Run(bs.name, func(b *testing.B) {
+			rng := rand.New(rand.NewSource(1))
+			s := bs.new()
+			for i := 0; i < b.N; i++ {
+				s.Add(rng.Intn(1 << 20))
+			}
+		})
+	}
+}
+
+func BenchmarkSet_Has(b *testing.B) {
+	for _, bs := range benchSets {
+		b.Run(bs.name, func(b *testing.B) {
+			s := bs.new()
+			for i := 0; i < 100000; i++ {
+				s.Add(i)
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				s.Has(i % 100000)
+			}
+		})
+	}
+}
+
+func BenchmarkSet_GetSlice(b *testing.B) {
+	for _, bs := range benchSets {
+		b.Run(bs.name, func(b *testing.B) {
+			s := bs.new()
+			for i := 0; i < 10000; i++ {
+				s.Add(i)
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				s.GetSlice()
+			}
+		})
+	}
+}
+
+func TestIntervalSet_RemoveAllSplitsRuns(t *testing.T) {
+	s := intervalsOf(1, 2, 3, 4, 5)
+
+	assert.Equal(t, 1, s.RemoveAll(3))
+	assert.Equal(t, []run{{1, 2}, {4, 5}}, s.runs)
+
+	assert.Equal(t, 2, s.RemoveAll(1, 5))
+	assert.Equal(t, []run{{2, 2}, {4, 4}}, s.runs)
+
+	assert.Equal(t, 1, s.RemoveAll(2))
+	assert.Equal(t, []run{{4, 4}}, s.runs)
+	assert.Equal(t, 1, s.Len())
+}
+
+func TestIntervalSet_DrainNTakesSmallestFirst(t *testing.T) {
+	s := intervalsOf(10, 11, 1, 2, 3)
+
+	assert.Equal(t, []int{1, 2}, s.DrainN(2))
+	assert.Equal(t, []int{3, 10}, s.DrainN(2))
+	assert.Equal(t, []run{{11, 11}}, s.runs)
+}
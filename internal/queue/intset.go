@@ -1,7 +1,8 @@
 package queue
 
 import (
-	"sync"
+	"iter"
+	"slices"
 )
 
 // IntSet defines the interface for thread-safe integer set operations.
@@ -10,91 +11,84 @@ import (
 type IntSet interface {
 	// Add inserts an integer into the set, returns true if newly added
 	Add(int) bool
-	
+
+	// AddAll inserts every given integer, returns how many were new
+	AddAll(...int) int
+
+	// RemoveAll deletes every given integer, returns how many were present
+	RemoveAll(...int) int
+
 	// Has checks if an integer exists in the set
 	Has(int) bool
-	
+
+	// Len returns the number of integers in the set
+	Len() int
+
+	// GetSlice returns every integer in the set in no particular order
+	GetSlice() []int
+
+	// Snapshot returns a sorted copy of the set
+	Snapshot() []int
+
+	// All iterates over a snapshot of the set in ascending order
+	All() iter.Seq[int]
+
+	// Diff returns the integers in this set but not in other, sorted
+	Diff(other IntSet) []int
+
+	// DrainN removes and returns up to n integers
+	DrainN(n int) []int
+
 	// Clear removes all elements from the set
 	Clear()
 }
 
-// intSet implements a thread-safe integer set using a map and RWMutex.
-// Forms the foundation for both Messages and Peer queue implementations
-// through composition, providing shared concurrent access patterns.
+// intSet implements IntSet on top of the generic map-backed Set, adding
+// the ordered views that only make sense for integers. Forms the foundation
+// for both Messages and Peer queue implementations through composition.
 type intSet struct {
-	// MU provides read-write mutex protection for concurrent access
-	// Uses RWMutex to allow multiple concurrent readers when appropriate
-	MU sync.RWMutex
-	
-	// Values stores integers as map keys with empty struct values
-	// Empty struct{} uses zero memory, making this memory-efficient
-	Values map[int]struct{}
+	Set[int]
 }
 
 // newIntSet creates a new thread-safe integer set with initialized map.
 // Returns intSet by value since it will be embedded in other structs.
 // The mutex is zero-initialized and ready for concurrent use.
 func newIntSet() intSet {
-	return intSet{
-		Values: make(map[int]struct{}),
-	}
-}
-// newIntSetFromMap creates a new thread-safe integer set from a 
-func newIntSetFromMap(values map[int]struct{}) intSet {
-	copied := make(map[int]struct{}, len(values))
-	for k, v := range values {
-			copied[k] = v
-	}
-	return intSet{Values: copied}
+	return intSet{Set[int]{Values: make(map[int]struct{})}}
 }
 
-// Has checks if the given integer exists in the set.
-// Uses write lock for simplicity, though read lock could be used here.
-// Returns true if the value exists, false otherwise.
-func (s *intSet) Has(v int) bool {
-	s.MU.RLock()
-	defer s.MU.RUnlock()
-
-	_, exists := s.Values[v]
-	return exists
+// GetSlice returns all integers in the set as a slice.
+// Order of elements is not guaranteed due to map iteration semantics.
+func (s *intSet) GetSlice() []int {
+	return s.Set.Items()
 }
 
-// Add inserts an integer into the set if it doesn't already exist.
-// Returns true if the value was newly added, false if it already existed.
-// Uses write lock to ensure atomic check-and-insert operation.
-func (s *intSet) Add(v int) bool {
-	s.MU.Lock()
-	defer s.MU.Unlock()
-
-	if _, exists := s.Values[v]; exists {
-		return false
-	}
+// Snapshot returns the set's integers as a new slice in ascending order.
+// Unlike GetSlice the order is stable, at the cost of a sort.
+func (s *intSet) Snapshot() []int {
+	return Sorted(&s.Set)
+}
 
-	s.Values[v] = struct{}{}
-	return true
+// All iterates over a snapshot of the set in ascending order, so the set
+// may be modified during iteration.
+func (s *intSet) All() iter.Seq[int] {
+	return slices.Values(s.Snapshot())
 }
 
-// GetSlice returns all integers in the set as a slice.
-// Creates a new slice with pre-allocated capacity for efficiency.
-// Order of elements is not guaranteed due to map iteration semantics.
-func (s *intSet) GetSlice() []int {
-	s.MU.Lock()
-	defer s.MU.Unlock()
+// Diff returns the integers in s that are not in other, in ascending order.
+// Locks s and other one at a time, so it never deadlocks against a
+// concurrent other.Diff(s).
+func (s *intSet) Diff(other IntSet) []int {
+	return diff(s.Snapshot(), other)
+}
 
-	out := make([]int, 0, len(s.Values))
-	for v := range s.Values {
-		out = append(out, v)
+// diff keeps the members of sorted that other lacks.
+func diff(sorted []int, other IntSet) []int {
+	out := make([]int, 0)
+	for _, v := range sorted {
+		if !other.Has(v) {
+			out = append(out, v)
+		}
 	}
-
 	return out
 }
-
-// Clear removes all elements from the set by creating a new empty map.
-// More efficient than iterating and deleting individual elements.
-// Uses write lock to ensure atomic operation during map replacement.
-func (s *intSet) Clear() {
-	s.MU.Lock()
-	defer s.MU.Unlock()
-	
-	s.Values = make(map[int]struct{})
-}
\ No newline at end of file
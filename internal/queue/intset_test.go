@@ -224,3 +224,91 @@ func TestIntSet_NegativeValues(t *testing.T) {
 	assert.Contains(t, slice, -1)
 	assert.Contains(t, slice, -42)
 }
+
+// setKinds builds each IntSet implementation so the shared contract can be
+// checked against all of them.
+var setKinds = []struct {
+	name string
+	new  func() IntSet
+}{
+	{"map", func() IntSet { s := newIntSet(); return &s }},
+	{"intervals", func() IntSet { return newIntervalSet() }},
+	{"peer", func() IntSet { return NewPeerQueue() }},
+	{"messages", func() IntSet { return NewMessagesQueue(IntervalSet) }},
+}
+
+func TestIntSet_AddAllRemoveAll(t *testing.T) {
+	for _, k := range setKinds {
+		t.Run(k.name, func(t *testing.T) {
+			s := k.new()
+
+			assert.Equal(t, 4, s.AddAll(5, 1, 3, 2))
+			assert.Equal(t, 1, s.AddAll(3, 4))
+			assert.Equal(t, 5, s.Len())
+
+			assert.Equal(t, 2, s.RemoveAll(3, 9, 5))
+			assert.Equal(t, 3, s.Len())
+			assert.Equal(t, []int{1, 2, 4}, s.Snapshot())
+		})
+	}
+}
+
+func TestIntSet_SnapshotAndAllAreOrdered(t *testing.T) {
+	for _, k := range setKinds {
+		t.Run(k.name, func(t *testing.T) {
+			s := k.new()
+			s.AddAll(30, -2, 7, 8, 100)
+
+			assert.Equal(t, []int{-2, 7, 8, 30, 100}, s.Snapshot())
+
+			var got []int
+			for v := range s.All() {
+				// Mutating during iteration is allowed
+				s.Add(v + 1000)
+				got = append(got, v)
+			}
+			assert.Equal(t, []int{-2, 7, 8, 30, 100}, got)
+		})
+	}
+}
+
+func TestIntSet_Diff(t *testing.T) {
+	for _, k := range setKinds {
+		t.Run(k.name, func(t *testing.T) {
+			a, b := k.new(), k.new()
+			a.AddAll(1, 2, 3, 4, 5)
+			b.AddAll(2, 4, 6)
+
+			assert.Equal(t, []int{1, 3, 5}, a.Diff(b))
+			assert.Equal(t, []int{6}, b.Diff(a))
+			assert.Empty(t, a.Diff(a))
+
+			// Mixed implementations
+			m := newIntSet()
+			m.AddAll(1, 5)
+			assert.Equal(t, []int{2, 3, 4}, a.Diff(&m))
+		})
+	}
+}
+
+func TestIntSet_DrainN(t *testing.T) {
+	for _, k := range setKinds {
+		t.Run(k.name, func(t *testing.T) {
+			s := k.new()
+			assert.Nil(t, s.DrainN(3))
+
+			s.AddAll(1, 2, 3, 4, 5)
+			batch := s.DrainN(3)
+			assert.Len(t, batch, 3)
+			assert.Equal(t, 2, s.Len())
+			for _, v := range batch {
+				assert.False(t, s.Has(v))
+			}
+
+			rest := s.DrainN(10)
+			assert.Len(t, rest, 2)
+			assert.Equal(t, 0, s.Len())
+			assert.ElementsMatch(t, []int{1, 2, 3, 4, 5}, append(batch, rest...))
+		})
+	}
+}
@@ -1,81 +1,283 @@
 package queue
 
-import "time"
+import (
+	"sync"
+	"time"
+)
+
+// Both queue types are full sets.
+var (
+	_ IntSet = (*Peer)(nil)
+	_ IntSet = (*Messages)(nil)
+)
 
 // Peer represents a peer node's message queue with retry capabilities.
 // Embeds intSet for thread-safe integer set operations and adds
-// timer-based retry logic for handling failed message transmissions.
+// retry scheduling state for handling failed message transmissions.
 type Peer struct {
 	intSet
 
-	// Timer manages retry timeouts for failed message transmissions
-	// Stores pointer to timer so messages can be resent if they hang or drop
-	Timer *time.Timer
+	// Attempts counts transmissions of the current unacknowledged delta
+	Attempts int
+
+	// Backoff is the delay chosen after the most recent transmission
+	Backoff time.Duration
+
+	// RetryAt is when the unacknowledged delta may next be resent;
+	// the zero time means it may be sent immediately
+	RetryAt time.Time
 
 	// InFlight tracks messages currently being transmitted to this peer
 	// Used for acknowledgment handling and retry logic
 	InFlight []int
 
-	LastOK *time.Time
+	// InFlightID identifies the InFlight batch; the peer echoes it in
+	// delta_ok so only that exact batch is retired
+	InFlightID uint64
+
+	// lastBatchID is the most recently assigned batch ID
+	lastBatchID uint64
+
+	// CounterAcked is the highest g-counter version this peer has acknowledged
+	CounterAcked uint64
+
+	// TxnAcked is the length of the local transaction replication log this
+	// peer has acknowledged; write sets from this index onward are unsent
+	TxnAcked int
 }
 
 // NewPeerQueue creates a new peer queue with initialized thread-safe integer set.
-// Timer and InFlight fields are zero-initialized and managed by the gossip server.
+// Retry and InFlight fields are zero-initialized and managed by the gossip server.
 func NewPeerQueue() *Peer {
 	return &Peer{
 		intSet: newIntSet(),
 	}
 }
 
-func NewPeerQueueFromMap(baseSet map[int]struct{}) *Peer {
-	return &Peer{
-		intSet: newIntSetFromMap(baseSet),
-	}
-}
-
 // DrainBatch extracts up to 'limit' messages from the peer queue for transmission.
 // Removes drained messages from the queue and returns them as a slice.
-// Uses mutex locking to ensure thread-safe access during batch operations.
 // Returns nil if queue is empty, otherwise returns slice of message IDs.
+//
+// Deprecated: use DrainN.
 func (pq *Peer) DrainBatch(limit int) []int {
-	pq.MU.Lock()
+	return pq.DrainN(limit)
+}
+
+// NextBatch returns the batch that should be (re)sent to this peer along with
+// its ID. If a batch is still awaiting acknowledgment it is returned unchanged
+// so retransmissions reuse the same ID; otherwise up to limit messages are
+// drained into a new batch with a fresh ID. Returns 0 and nil if there is
+// nothing to send.
+func (pq *Peer) NextBatch(limit int) (uint64, []int) {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
 
-	if len(pq.Values) == 0 {
-		pq.MU.Unlock()
-		return nil
+	if pq.InFlight == nil {
+		batch := pq.drainLocked(limit)
+		if batch == nil {
+			return 0, nil
+		}
+		pq.lastBatchID++
+		pq.InFlightID = pq.lastBatchID
+		pq.InFlight = batch
 	}
+	return pq.InFlightID, pq.InFlight
+}
 
-	batchSize := limit
-	if len(pq.Values) < limit {
-		batchSize = len(pq.Values)
+// Ack retires the in-flight batch if id identifies it. Acks for older batches,
+// duplicates, and acks carrying no batch ID are ignored so a late ack can
+// never discard a newer batch. Returns true if a batch was retired.
+func (pq *Peer) Ack(id uint64) bool {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+
+	if id == 0 || pq.InFlight == nil || id != pq.InFlightID {
+		return false
 	}
-	batch := make([]int, 0, batchSize)
-	count := 0
-	for k := range pq.Values {
-		if count >= limit {
-			break
-		}
-		batch = append(batch, k)
-		delete(pq.Values, k)
-		count++
+	pq.InFlight = nil
+	pq.InFlightID = 0
+	return true
+}
+
+// RetryDue reports whether the unacknowledged delta may be sent at now.
+func (pq *Peer) RetryDue(now time.Time) bool {
+	pq.mu.RLock()
+	defer pq.mu.RUnlock()
+
+	return !now.Before(pq.RetryAt)
+}
+
+// MarkSent records a transmission at now and schedules the next retry after
+// the delay chosen by backoff, which is given the attempt number and the
+// previous delay. Returns the attempt number.
+func (pq *Peer) MarkSent(now time.Time, backoff func(attempt int, prev time.Duration) time.Duration) int {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+
+	pq.Attempts++
+	pq.Backoff = backoff(pq.Attempts, pq.Backoff)
+	pq.RetryAt = now.Add(pq.Backoff)
+	return pq.Attempts
+}
+
+// ResetRetry clears the backoff state so the next delta goes out immediately.
+func (pq *Peer) ResetRetry() {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+
+	pq.Attempts = 0
+	pq.Backoff = 0
+	pq.RetryAt = time.Time{}
+}
+
+// AckCounter records that the peer has merged counter state up to version.
+// Acks may arrive out of order, so only ever moves the watermark forward.
+// Reports whether it moved.
+func (pq *Peer) AckCounter(version uint64) bool {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+
+	if version <= pq.CounterAcked {
+		return false
+	}
+	pq.CounterAcked = version
+	return true
+}
+
+// CounterBehind reports whether the peer has yet to acknowledge the given
+// counter version and therefore needs a fresh snapshot.
+func (pq *Peer) CounterBehind(version uint64) bool {
+	pq.mu.RLock()
+	defer pq.mu.RUnlock()
+
+	return version > pq.CounterAcked
+}
+
+// AckTxn records that the peer has applied the replication log up to upto.
+// Like AckCounter, out-of-order acks never move the watermark backwards.
+// Reports whether it moved.
+func (pq *Peer) AckTxn(upto int) bool {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+
+	if upto <= pq.TxnAcked {
+		return false
 	}
-	pq.MU.Unlock()
+	pq.TxnAcked = upto
+	return true
+}
+
+// TxnFrom returns the replication log index the next delta should start at.
+func (pq *Peer) TxnFrom() int {
+	pq.mu.RLock()
+	defer pq.mu.RUnlock()
 
-	return batch
+	return pq.TxnAcked
 }
 
+// SetKind selects the data structure backing a Messages queue.
+type SetKind int
+
+const (
+	// MapSet stores each value as a map key; fast for scattered values
+	MapSet SetKind = iota
+
+	// IntervalSet stores runs of consecutive values, which keeps memory
+	// proportional to the number of gaps rather than the number of values
+	// and returns values in ascending order
+	IntervalSet
+)
+
 // Messages represents the global message storage for the distributed system.
-// Embeds intSet to provide thread-safe storage and retrieval of all seen messages
+// Wraps an IntSet to provide thread-safe storage and retrieval of all seen messages
 // across the entire gossip network. Used for deduplication and state management.
 type Messages struct {
-	intSet
+	IntSet
+
+	// onAdd, if set, runs for each value newly added through Insert
+	onAdd func(int) error
+
+	// mu guards inserting, the values whose Insert is still running onAdd
+	mu        sync.Mutex
+	inserting map[int]*insertion
+}
+
+// insertion is an Insert still running the OnAdd hook; done is closed once
+// err holds the hook's outcome.
+type insertion struct {
+	done chan struct{}
+	err  error
+}
+
+// OnAdd registers fn to run for each value newly added through Insert,
+// before the value becomes visible and outside the set's lock; used to
+// persist values as they arrive. Add and AddAll bypass it. Must be called
+// before the queue is shared between goroutines.
+func (m *Messages) OnAdd(fn func(int) error) {
+	m.onAdd = fn
+}
+
+// Insert adds v and reports whether it was new, running the OnAdd hook for
+// a new value first. v only becomes visible once the hook has accepted it;
+// if the hook fails v is not added and the error returned. A concurrent
+// Insert of the same value waits for the hook and shares its error, so it
+// never reports v as already held before the hook has accepted it.
+func (m *Messages) Insert(v int) (bool, error) {
+	if m.onAdd == nil {
+		return m.IntSet.Add(v), nil
+	}
+
+	m.mu.Lock()
+	if m.IntSet.Has(v) {
+		m.mu.Unlock()
+		return false, nil
+	}
+	if in, ok := m.inserting[v]; ok {
+		m.mu.Unlock()
+		<-in.done
+		return false, in.err
+	}
+	in := &insertion{done: make(chan struct{})}
+	if m.inserting == nil {
+		m.inserting = make(map[int]*insertion)
+	}
+	m.inserting[v] = in
+	m.mu.Unlock()
+
+	in.err = m.onAdd(v)
+
+	m.mu.Lock()
+	if in.err == nil {
+		m.IntSet.Add(v)
+	}
+	delete(m.inserting, v)
+	m.mu.Unlock()
+	close(in.done)
+	return in.err == nil, in.err
+}
+
+// Inserting returns the values whose Insert is still running the OnAdd
+// hook and so are not visible yet, in no particular order. Snapshots that
+// must cover everything the hook may already have recorded include them.
+func (m *Messages) Inserting() []int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	out := make([]int, 0, len(m.inserting))
+	for v := range m.inserting {
+		out = append(out, v)
+	}
+	return out
 }
 
 // NewMessagesQueue creates a new global message queue with thread-safe integer set.
 // This queue stores all messages seen by the node for deduplication and
 // serves as the authoritative source of truth for broadcast state.
-func NewMessagesQueue() *Messages {
-	return &Messages{
-		intSet: newIntSet(),
+// kind selects the set backing it.
+func NewMessagesQueue(kind SetKind) *Messages {
+	if kind == IntervalSet {
+		return &Messages{IntSet: newIntervalSet()}
 	}
+	s := newIntSet()
+	return &Messages{IntSet: &s}
 }
@@ -2,9 +2,11 @@ package queue
 
 import (
 	// --- Standard Lib ---
+	"errors"
 	"fmt"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -82,4 +84,155 @@ func TestPeerQueue_ConcurrentAccess(t *testing.T) {
 			totalDrained += len(batch)
 	}
 	assert.LessOrEqual(t, totalDrained, 10)
-}
\ No newline at end of file
+}
+
+func TestPeerQueue_AckCounter(t *testing.T) {
+	pq := NewPeerQueue()
+	assert.True(t, pq.CounterBehind(1))
+
+	assert.True(t, pq.AckCounter(3))
+	assert.False(t, pq.CounterBehind(3))
+	assert.True(t, pq.CounterBehind(4))
+
+	// A late ack for an older version must not move the watermark back
+	assert.False(t, pq.AckCounter(1))
+	assert.Equal(t, uint64(3), pq.CounterAcked)
+}
+
+func TestPeerQueue_NextBatchReusesInFlight(t *testing.T) {
+	pq := NewPeerQueue()
+	id, batch := pq.NextBatch(10)
+	assert.Equal(t, uint64(0), id)
+	assert.Nil(t, batch)
+
+	pq.Add(1)
+	id, batch = pq.NextBatch(10)
+	assert.Equal(t, uint64(1), id)
+	assert.Equal(t, []int{1}, batch)
+
+	// Newly queued messages wait until the in-flight batch is acknowledged
+	pq.Add(2)
+	resendID, resend := pq.NextBatch(10)
+	assert.Equal(t, id, resendID)
+	assert.Equal(t, batch, resend)
+}
+
+func TestPeerQueue_AckRetiresOnlyMatchingBatch(t *testing.T) {
+	pq := NewPeerQueue()
+	pq.Add(1)
+	first, _ := pq.NextBatch(10)
+
+	assert.True(t, pq.Ack(first))
+	pq.Add(2)
+	second, batch := pq.NextBatch(10)
+	assert.NotEqual(t, first, second)
+
+	// A late ack for the first batch must not retire the second
+	assert.False(t, pq.Ack(first))
+	id, inflight := pq.NextBatch(10)
+	assert.Equal(t, second, id)
+	assert.Equal(t, batch, inflight)
+
+	assert.True(t, pq.Ack(second))
+	assert.Nil(t, pq.InFlight)
+}
+
+func TestPeerQueue_DuplicateAck(t *testing.T) {
+	pq := NewPeerQueue()
+	pq.Add(1)
+	id, _ := pq.NextBatch(10)
+
+	assert.True(t, pq.Ack(id))
+	assert.False(t, pq.Ack(id))
+
+	// Ack without a batch ID never retires anything
+	pq.Add(2)
+	pq.NextBatch(10)
+	assert.False(t, pq.Ack(0))
+	assert.NotNil(t, pq.InFlight)
+}
+
+func TestPeerQueue_ReorderedAcks(t *testing.T) {
+	pq := NewPeerQueue()
+	var ids []uint64
+	for i := 0; i < 3; i++ {
+		pq.Add(i)
+		id, _ := pq.NextBatch(10)
+		ids = append(ids, id)
+		assert.True(t, pq.Ack(id))
+	}
+
+	// Replaying every ack out of order after the fact is harmless
+	pq.Add(99)
+	current, _ := pq.NextBatch(10)
+	for _, i := range []int{2, 0, 1} {
+		assert.False(t, pq.Ack(ids[i]))
+	}
+	assert.Equal(t, current, pq.InFlightID)
+	assert.Equal(t, []int{99}, pq.InFlight)
+}
+
+func TestMessages_InsertRunsOnAddForNewValuesOnly(t *testing.T) {
+	m := NewMessagesQueue(IntervalSet)
+	m.AddAll(1, 2)
+
+	var got []int
+	m.OnAdd(func(v int) error {
+		got = append(got, v)
+		return nil
+	})
+
+	added, err := m.Insert(3)
+	assert.NoError(t, err)
+	assert.True(t, added)
+	added, err = m.Insert(3)
+	assert.NoError(t, err)
+	assert.False(t, added)
+	m.Add(4)
+	assert.Equal(t, []int{3}, got, "Add bypasses the hook")
+}
+
+func TestMessages_InsertUndoesFailedOnAdd(t *testing.T) {
+	m := NewMessagesQueue(MapSet)
+	m.OnAdd(func(int) error { return errors.New("disk full") })
+
+	added, err := m.Insert(7)
+	assert.EqualError(t, err, "disk full")
+	assert.False(t, added)
+	assert.False(t, m.Has(7))
+}
+
+func TestMessages_InsertHidesValueUntilOnAddAccepts(t *testing.T) {
+	m := NewMessagesQueue(MapSet)
+	entered, release := make(chan struct{}), make(chan struct{})
+	var once sync.Once
+	m.OnAdd(func(v int) error {
+		once.Do(func() { close(entered) })
+		<-release
+		return errors.New("disk full")
+	})
+
+	first := make(chan error, 1)
+	go func() {
+		_, err := m.Insert(7)
+		first <- err
+	}()
+	<-entered
+	assert.False(t, m.Has(7), "not visible while the hook runs")
+	assert.Equal(t, []int{7}, m.Inserting())
+
+	// A duplicate waits for the first insert and shares its failure
+	second := make(chan error, 1)
+	go func() {
+		added, err := m.Insert(7)
+		assert.False(t, added)
+		second <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+
+	assert.EqualError(t, <-first, "disk full")
+	assert.EqualError(t, <-second, "disk full")
+	assert.False(t, m.Has(7))
+	assert.Empty(t, m.Inserting())
+}
@@ -0,0 +1,165 @@
+package queue
+
+import (
+	// --- Standard Lib ---
+	"cmp"
+	"iter"
+	"slices"
+	"sync"
+)
+
+// Set is a thread-safe set of any comparable type, backed by a map and
+// RWMutex. It holds node IDs, transaction keys or composite struct keys
+// as readily as integers; intSet builds the IntSet interface on top of it.
+// The zero value is an empty set ready to use.
+type Set[T comparable] struct {
+	// mu provides read-write mutex protection for concurrent access
+	// Uses RWMutex to allow multiple concurrent readers when appropriate
+	mu sync.RWMutex
+
+	// Values stores members as map keys with empty struct values
+	// Empty struct{} uses zero memory, making this memory-efficient
+	// Kept exported for existing callers; touch it only while holding mu
+	Values map[T]struct{}
+}
+
+// NewSet creates a set holding the given members.
+func NewSet[T comparable](members ...T) *Set[T] {
+	s := &Set[T]{Values: make(map[T]struct{}, len(members))}
+	for _, v := range members {
+		s.Values[v] = struct{}{}
+	}
+	return s
+}
+
+// Has checks if v is a member of the set.
+func (s *Set[T]) Has(v T) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, exists := s.Values[v]
+	return exists
+}
+
+// Add inserts v into the set if it doesn't already exist.
+// Returns true if the value was newly added, false if it already existed.
+// Uses write lock to ensure atomic check-and-insert operation.
+func (s *Set[T]) Add(v T) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.addLocked(v)
+}
+
+// AddAll inserts every given value under a single lock.
+// Returns the number of values that were not already present.
+func (s *Set[T]) AddAll(vs ...T) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	added := 0
+	for _, v := range vs {
+		if s.addLocked(v) {
+			added++
+		}
+	}
+	return added
+}
+
+// addLocked implements Add. Caller must hold s.mu.
+func (s *Set[T]) addLocked(v T) bool {
+	if _, exists := s.Values[v]; exists {
+		return false
+	}
+	if s.Values == nil {
+		s.Values = make(map[T]struct{})
+	}
+	s.Values[v] = struct{}{}
+	return true
+}
+
+// RemoveAll deletes every given value under a single lock.
+// Returns the number of values that were present.
+func (s *Set[T]) RemoveAll(vs ...T) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	removed := 0
+	for _, v := range vs {
+		if _, exists := s.Values[v]; exists {
+			delete(s.Values, v)
+			removed++
+		}
+	}
+	return removed
+}
+
+// Len returns the number of members.
+func (s *Set[T]) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.Values)
+}
+
+// Items returns the members as a new slice.
+// Order of elements is not guaranteed due to map iteration semantics;
+// use Sorted for ordered types.
+func (s *Set[T]) Items() []T {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]T, 0, len(s.Values))
+	for v := range s.Values {
+		out = append(out, v)
+	}
+	return out
+}
+
+// All iterates over a snapshot of the set, so the set may be modified
+// during iteration. Order is unspecified.
+func (s *Set[T]) All() iter.Seq[T] {
+	return slices.Values(s.Items())
+}
+
+// DrainN removes and returns up to n members, for sending a set out in
+// batches. Which members are drained is not specified. Returns nil when empty.
+func (s *Set[T]) DrainN(n int) []T {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.drainLocked(n)
+}
+
+// drainLocked implements DrainN. Caller must hold s.mu.
+func (s *Set[T]) drainLocked(n int) []T {
+	if len(s.Values) == 0 || n <= 0 {
+		return nil
+	}
+
+	batch := make([]T, 0, min(n, len(s.Values)))
+	for k := range s.Values {
+		if len(batch) >= n {
+			break
+		}
+		batch = append(batch, k)
+		delete(s.Values, k)
+	}
+	return batch
+}
+
+// Clear removes all elements from the set by creating a new empty map.
+// More efficient than iterating and deleting individual elements.
+func (s *Set[T]) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Values = make(map[T]struct{})
+}
+
+// Sorted returns the members of s in ascending order.
+func Sorted[T cmp.Ordered](s *Set[T]) []T {
+	out := s.Items()
+	slices.Sort(out)
+	return out
+}
@@ -0,0 +1,85 @@
+package queue
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSet_Strings(t *testing.T) {
+	s := NewSet("n1", "n2")
+
+	assert.True(t, s.Has("n1"))
+	assert.False(t, s.Add("n2"))
+	assert.True(t, s.Add("n0"))
+	assert.Equal(t, []string{"n0", "n1", "n2"}, Sorted(s))
+	assert.Equal(t, 1, s.RemoveAll("n1", "n9"))
+	assert.Equal(t, 2, s.Len())
+}
+
+func TestSet_CompositeKeys(t *testing.T) {
+	type key struct {
+		node string
+		seq  int
+	}
+	var s Set[key]
+
+	// The zero value is usable
+	assert.True(t, s.Add(key{"n1", 1}))
+	assert.Equal(t, 1, s.AddAll(key{"n1", 1}, key{"n2", 1}))
+	assert.True(t, s.Has(key{"n2", 1}))
+	assert.False(t, s.Has(key{"n2", 2}))
+	assert.ElementsMatch(t, []key{{"n1", 1}, {"n2", 1}}, s.Items())
+}
+
+func TestSet_DrainN(t *testing.T) {
+	s := NewSet("a", "b", "c")
+
+	batch := s.DrainN(2)
+	assert.Len(t, batch, 2)
+	assert.Equal(t, 1, s.Len())
+	for _, v := range batch {
+		assert.False(t, s.Has(v))
+	}
+	assert.Len(t, s.DrainN(5), 1)
+	assert.Nil(t, s.DrainN(5))
+}
+
+func TestSet_All(t *testing.T) {
+	s := NewSet("x", "y")
+
+	var got []string
+	for v := range s.All() {
+		s.Add(v + v)
+		got = append(got, v)
+	}
+	assert.ElementsMatch(t, []string{"x", "y"}, got)
+	assert.Equal(t, 4, s.Len())
+}
+
+func TestSet_ConcurrentAdd(t *testing.T) {
+	var s Set[int]
+	var wg sync.WaitGroup
+	added := make([]int, 8)
+
+	for w := range added {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				if s.Add(i) {
+					added[w]++
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	total := 0
+	for _, n := range added {
+		total += n
+	}
+	assert.Equal(t, 100, total, "each value is newly added exactly once")
+	assert.Equal(t, 100, s.Len())
+}
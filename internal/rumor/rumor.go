@@ -0,0 +1,107 @@
+// Package rumor tracks the state of push-pull rumor mongering: which values
+// a node is still actively spreading ("hot" rumors) and how often peers have
+// answered a push by saying they already knew a value.
+//
+// This is the feedback/counter variant from Demers et al.: a rumor's counter
+// only moves when a peer reports the value as already known, and the rumor
+// is retired once the counter reaches a limit. Retired values are no longer
+// pushed; the few nodes rumor mongering misses are left to anti-entropy.
+package rumor
+
+import (
+	// --- Standard Lib ---
+	"math/rand/v2"
+	"slices"
+	"sync"
+)
+
+// Mill holds the hot rumors of one node. It is safe for concurrent use.
+type Mill struct {
+	mu sync.Mutex
+
+	// hot maps each rumor still being spread to the number of peers that
+	// have reported already knowing it
+	hot map[int]int
+}
+
+// NewMill creates a mill with no hot rumors.
+func NewMill() *Mill {
+	return &Mill{hot: make(map[int]int)}
+}
+
+// Add makes each value a hot rumor, restarting its counter if it was
+// already hot. Used for values the node has just learned, and for values
+// found missing elsewhere that should be spread again.
+func (m *Mill) Add(vs ...int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, v := range vs {
+		m.hot[v] = 0
+	}
+}
+
+// Hot returns up to n hot rumors, least-repeated first and then in
+// ascending order, so a full batch favours rumors that are still news.
+func (m *Mill) Hot(n int) []int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	out := make([]int, 0, len(m.hot))
+	for v := range m.hot {
+		out = append(out, v)
+	}
+	slices.SortFunc(out, func(a, b int) int {
+		if c := m.hot[a] - m.hot[b]; c != 0 {
+			return c
+		}
+		return a - b
+	})
+	if len(out) > n {
+		out = out[:n]
+	}
+	return out
+}
+
+// Feedback records that a peer already knew each of the given values.
+// Rumors reported known limit times are retired. Values that are not hot
+// are ignored. Returns how many rumors were retired.
+func (m *Mill) Feedback(known []int, limit int) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	retired := 0
+	for _, v := range known {
+		n, ok := m.hot[v]
+		if !ok {
+			continue
+		}
+		if n+1 >= limit {
+			delete(m.hot, v)
+			retired++
+			continue
+		}
+		m.hot[v] = n + 1
+	}
+	return retired
+}
+
+// Len returns the number of hot rumors.
+func (m *Mill) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return len(m.hot)
+}
+
+// Sample returns up to k distinct items chosen uniformly at random using
+// rng, in the order chosen. items is not modified.
+func Sample[T any](rng *rand.Rand, items []T, k int) []T {
+	pool := slices.Clone(items)
+	k = min(k, len(pool))
+	for i := 0; i < k; i++ {
+		j := i + rng.IntN(len(pool)-i)
+		pool[i], pool[j] = pool[j], pool[i]
+	}
+	return pool[:k]
+}
@@ -0,0 +1,50 @@
+package rumor
+
+import (
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMill_FeedbackRetiresAtLimit(t *testing.T) {
+	m := NewMill()
+	m.Add(1, 2, 3)
+
+	assert.Equal(t, 0, m.Feedback([]int{1, 2}, 2))
+	assert.Equal(t, []int{3, 1, 2}, m.Hot(10), "least repeated first")
+
+	assert.Equal(t, 1, m.Feedback([]int{1, 42}, 2), "unknown values are ignored")
+	assert.Equal(t, []int{3, 2}, m.Hot(10))
+	assert.Equal(t, 2, m.Len())
+
+	// Re-adding restarts the counter
+	m.Add(2)
+	assert.Equal(t, 0, m.Feedback([]int{2}, 2))
+	assert.Equal(t, 2, m.Len())
+}
+
+func TestMill_HotCapsBatch(t *testing.T) {
+	m := NewMill()
+	m.Add(5, 4, 3, 2, 1)
+
+	assert.Equal(t, []int{1, 2}, m.Hot(2))
+	assert.Empty(t, NewMill().Hot(2))
+}
+
+func TestSample(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 1))
+	items := []string{"n1", "n2", "n3", "n4", "n5"}
+
+	got := Sample(rng, items, 3)
+	assert.Len(t, got, 3)
+	for _, v := range got {
+		assert.Contains(t, items, v)
+	}
+	assert.Len(t, slices.Compact(slices.Sorted(slices.Values(got))), 3, "distinct")
+	assert.Equal(t, []string{"n1", "n2", "n3", "n4", "n5"}, items, "input untouched")
+
+	assert.ElementsMatch(t, items, Sample(rng, items, 10))
+	assert.Empty(t, Sample(rng, items, 0))
+}
@@ -0,0 +1,547 @@
+// Package sim provides a deterministic in-process network for testing
+// gossip nodes without Maelstrom. Messages travel on a virtual clock with
+// configurable latency, loss, duplication and partitions, all driven by a
+// seeded random source, so a scenario replays identically from its seed.
+//
+// The network is single-threaded: handlers run synchronously on the
+// goroutine that calls RunFor. Sends are safe from any goroutine, so nodes
+// may hand work to helper goroutines as long as they wait for it before
+// returning to keep a run reproducible. SyncRPC is delivered immediately
+// rather than through the latency queue, since a blocking call could never
+// complete while its own goroutine is the one advancing time.
+package sim
+
+import (
+	// --- Standard Lib ---
+	"container/heap"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"time"
+
+	// --- Third Party ---
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+)
+
+// Config controls the behaviour of simulated links.
+type Config struct {
+	// Seed initializes the random source for every network decision
+	Seed uint64
+
+	// Latency is the minimum one-way delay of every message
+	Latency time.Duration
+
+	// Jitter adds a uniformly random delay in [0, Jitter) on top of Latency
+	Jitter time.Duration
+
+	// DropRate is the probability a message is silently lost
+	DropRate float64
+
+	// DupRate is the probability a message is delivered twice
+	DupRate float64
+}
+
+// Service answers RPCs addressed to a non-node destination, such as
+// Maelstrom's KV services. kv.Fake satisfies it.
+type Service interface {
+	SyncRPC(ctx context.Context, dest string, body any) (maelstrom.Message, error)
+}
+
+// Stats counts what happened to messages on the network.
+type Stats struct {
+	Sent       int
+	Delivered  int
+	Dropped    int
+	Duplicated int
+	Blocked    int
+}
+
+// envelope is a message scheduled for delivery.
+type envelope struct {
+	at  time.Time
+	seq uint64
+	msg maelstrom.Message
+}
+
+// inbox orders envelopes by delivery time, then by send order.
+type inbox []envelope
+
+func (q inbox) Len() int { return len(q) }
+func (q inbox) Less(i, j int) bool {
+	if !q[i].at.Equal(q[j].at) {
+		return q[i].at.Before(q[j].at)
+	}
+	return q[i].seq < q[j].seq
+}
+func (q inbox) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+func (q *inbox) Push(x any)   { *q = append(*q, x.(envelope)) }
+func (q *inbox) Pop() any {
+	old := *q
+	e := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return e
+}
+
+// rpcKey identifies an outstanding synchronous RPC.
+type rpcKey struct {
+	caller string
+	msgID  int
+}
+
+// timer is a periodic callback on the virtual clock.
+type timer struct {
+	every time.Duration
+	next  time.Time
+	fn    func()
+}
+
+// Network is a simulated cluster of nodes plus any client traffic.
+type Network struct {
+	mu  sync.Mutex
+	cfg Config
+	rng *rand.Rand
+
+	now   time.Time
+	seq   uint64
+	queue inbox
+
+	nodes    map[string]*Node
+	ids      []string
+	services map[string]Service
+	timers   []*timer
+
+	// blocked holds directed links that partitions currently cut
+	blocked map[[2]string]bool
+
+	// replies collects messages addressed to clients, keyed by client ID
+	replies map[string][]maelstrom.Message
+
+	// rpcs holds replies to in-progress SyncRPC calls, keyed by the calling
+	// node and the msg_id it used; nil until the reply arrives
+	rpcs map[rpcKey]*maelstrom.Message
+
+	stats Stats
+}
+
+// NewNetwork creates a network with one initialized node per ID.
+func NewNetwork(cfg Config, ids []string) *Network {
+	n := &Network{
+		cfg:      cfg,
+		rng:      rand.New(rand.NewPCG(cfg.Seed, cfg.Seed)),
+		now:      time.Unix(0, 0),
+		nodes:    make(map[string]*Node),
+		ids:      slices.Clone(ids),
+		services: make(map[string]Service),
+		blocked:  make(map[[2]string]bool),
+		replies:  make(map[string][]maelstrom.Message),
+		rpcs:     make(map[rpcKey]*maelstrom.Message),
+	}
+	for _, id := range ids {
+		n.nodes[id] = &Node{
+			net:      n,
+			id:       id,
+			handlers: make(map[string]maelstrom.HandlerFunc),
+		}
+	}
+	return n
+}
+
+// Node returns the simulated node with the given ID, or nil.
+func (n *Network) Node(id string) *Node {
+	return n.nodes[id]
+}
+
+// Now returns the current virtual time. Lets the network act as a clock
+// for anything that schedules against time.
+func (n *Network) Now() time.Time {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	return n.now
+}
+
+// Stats returns a copy of the network counters.
+func (n *Network) Stats() Stats {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	return n.stats
+}
+
+// AddService routes RPCs addressed to name to svc.
+func (n *Network) AddService(name string, svc Service) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.services[name] = svc
+}
+
+// Every schedules fn to run every d of virtual time, first at now+d.
+func (n *Network) Every(d time.Duration, fn func()) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.timers = append(n.timers, &timer{every: d, next: n.now.Add(d), fn: fn})
+}
+
+// Partition splits the nodes into isolated groups. Nodes not listed in any
+// group can still reach everyone. Clients are never partitioned, and are
+// likewise exempt from drops and duplication.
+func (n *Network) Partition(groups ...[]string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.blocked = make(map[[2]string]bool)
+	for i, a := range groups {
+		for j, b := range groups {
+			if i == j {
+				continue
+			}
+			for _, src := range a {
+				for _, dst := range b {
+					n.blocked[[2]string{src, dst}] = true
+				}
+			}
+		}
+	}
+}
+
+// Heal removes all partitions.
+func (n *Network) Heal() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.blocked = make(map[[2]string]bool)
+}
+
+// Init sends every node its init message, as Maelstrom does at startup.
+func (n *Network) Init() {
+	for _, id := range n.ids {
+		node := n.nodes[id]
+		body, _ := json.Marshal(maelstrom.InitMessageBody{
+			MessageBody: maelstrom.MessageBody{Type: "init"},
+			NodeID:      id,
+			NodeIDs:     n.ids,
+		})
+		node.deliver(maelstrom.Message{Src: "c0", Dest: id, Body: body})
+	}
+}
+
+// Inject sends a client request body from client to node dest.
+func (n *Network) Inject(client, dest string, body any) error {
+	return n.send(client, dest, body)
+}
+
+// Replies returns every message delivered to client so far.
+func (n *Network) Replies(client string) []maelstrom.Message {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	return slices.Clone(n.replies[client])
+}
+
+// RunFor advances the virtual clock by d, delivering messages and firing
+// timers in time order.
+func (n *Network) RunFor(d time.Duration) {
+	n.RunUntil(func() bool { return false }, d)
+}
+
+// RunUntil advances the virtual clock until cond returns true or limit has
+// elapsed. cond is checked after every event. Returns whether cond was met.
+func (n *Network) RunUntil(cond func() bool, limit time.Duration) bool {
+	n.mu.Lock()
+	deadline := n.now.Add(limit)
+	n.mu.Unlock()
+
+	for {
+		if cond() {
+			return true
+		}
+		if !n.step(deadline) {
+			return cond()
+		}
+	}
+}
+
+// step runs the next event at or before deadline. Returns false, with the
+// clock set to deadline, if there is none.
+func (n *Network) step(deadline time.Time) bool {
+	n.mu.Lock()
+
+	var next *timer
+	for _, t := range n.timers {
+		if next == nil || t.next.Before(next.next) {
+			next = t
+		}
+	}
+
+	haveMsg := n.queue.Len() > 0 && !n.queue[0].at.After(deadline)
+	haveTimer := next != nil && !next.next.After(deadline)
+
+	switch {
+	case haveMsg && (!haveTimer || !next.next.Before(n.queue[0].at)):
+		e := heap.Pop(&n.queue).(envelope)
+		n.now = e.at
+		n.mu.Unlock()
+		n.dispatch(e.msg)
+		return true
+
+	case haveTimer:
+		n.now = next.next
+		next.next = next.next.Add(next.every)
+		n.mu.Unlock()
+		next.fn()
+		return true
+
+	default:
+		n.now = deadline
+		n.mu.Unlock()
+		return false
+	}
+}
+
+// dispatch hands a delivered message to its destination.
+func (n *Network) dispatch(msg maelstrom.Message) {
+	if node, ok := n.nodes[msg.Dest]; ok {
+		n.mu.Lock()
+		n.stats.Delivered++
+		n.mu.Unlock()
+		node.deliver(msg)
+		return
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.stats.Delivered++
+	n.replies[msg.Dest] = append(n.replies[msg.Dest], msg)
+}
+
+// send schedules body from src to dest, applying partitions, loss,
+// duplication and latency.
+func (n *Network) send(src, dest string, body any) error {
+	buf, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	msg := maelstrom.Message{Src: src, Dest: dest, Body: buf}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	// Replies to a synchronous RPC bypass the queue and go to the caller
+	var head maelstrom.MessageBody
+	if err := json.Unmarshal(buf, &head); err == nil && head.InReplyTo != 0 {
+		key := rpcKey{caller: dest, msgID: head.InReplyTo}
+		if reply, ok := n.rpcs[key]; ok && reply == nil {
+			n.rpcs[key] = &msg
+			return nil
+		}
+	}
+
+	n.stats.Sent++
+
+	// Faults only apply between nodes; Maelstrom never loses client traffic
+	_, srcNode := n.nodes[src]
+	_, destNode := n.nodes[dest]
+	faulty := srcNode && destNode
+
+	if faulty && n.blocked[[2]string{src, dest}] {
+		n.stats.Blocked++
+		return nil
+	}
+	if faulty && n.cfg.DropRate > 0 && n.rng.Float64() < n.cfg.DropRate {
+		n.stats.Dropped++
+		return nil
+	}
+
+	copies := 1
+	if faulty && n.cfg.DupRate > 0 && n.rng.Float64() < n.cfg.DupRate {
+		n.stats.Duplicated++
+		copies = 2
+	}
+	for i := 0; i < copies; i++ {
+		delay := n.cfg.Latency
+		if n.cfg.Jitter > 0 {
+			delay += time.Duration(n.rng.Int64N(int64(n.cfg.Jitter)))
+		}
+		n.seq++
+		heap.Push(&n.queue, envelope{at: n.now.Add(delay), seq: n.seq, msg: msg})
+	}
+	return nil
+}
+
+// Node is a simulated cluster member. It implements the same surface as
+// *maelstrom.Node that gossip.Server depends on.
+type Node struct {
+	net *Network
+	id  string
+
+	// nodeIDs is set by the init message, mirroring Maelstrom
+	nodeIDs []string
+
+	handlers map[string]maelstrom.HandlerFunc
+
+	// nextMsgID numbers outgoing RPCs
+	nextMsgID int
+
+	// errors records handler failures for tests to inspect
+	errors []error
+}
+
+// ID returns the node's ID.
+func (nd *Node) ID() string {
+	return nd.id
+}
+
+// NodeIDs returns every node in the cluster; empty until init.
+func (nd *Node) NodeIDs() []string {
+	return nd.nodeIDs
+}
+
+// Handle registers a handler for a message type.
+func (nd *Node) Handle(typ string, fn maelstrom.HandlerFunc) {
+	if _, ok := nd.handlers[typ]; ok {
+		panic(fmt.Sprintf("duplicate message handler for %q message type", typ))
+	}
+	nd.handlers[typ] = fn
+}
+
+// Send schedules a message to dest through the simulated network.
+func (nd *Node) Send(dest string, body any) error {
+	return nd.net.send(nd.id, dest, body)
+}
+
+// Reply responds to req with body, setting in_reply_to like Maelstrom does.
+func (nd *Node) Reply(req maelstrom.Message, body any) error {
+	var head maelstrom.MessageBody
+	if err := json.Unmarshal(req.Body, &head); err != nil {
+		return err
+	}
+	b, err := withField(body, "in_reply_to", head.MsgID)
+	if err != nil {
+		return err
+	}
+	return nd.Send(req.Src, b)
+}
+
+// SyncRPC delivers body to dest immediately and returns its reply.
+// Partitioned destinations fail with context.DeadlineExceeded, as a real
+// call would once its deadline passed.
+func (nd *Node) SyncRPC(ctx context.Context, dest string, body any) (maelstrom.Message, error) {
+	n := nd.net
+	n.mu.Lock()
+	svc, isService := n.services[dest]
+	blocked := n.blocked[[2]string{nd.id, dest}] || n.blocked[[2]string{dest, nd.id}]
+	nd.nextMsgID++
+	msgID := nd.nextMsgID
+	n.mu.Unlock()
+
+	if isService {
+		return svc.SyncRPC(ctx, dest, body)
+	}
+	target, ok := n.nodes[dest]
+	if !ok || blocked {
+		return maelstrom.Message{}, context.DeadlineExceeded
+	}
+
+	b, err := withField(body, "msg_id", msgID)
+	if err != nil {
+		return maelstrom.Message{}, err
+	}
+	buf, err := json.Marshal(b)
+	if err != nil {
+		return maelstrom.Message{}, err
+	}
+
+	key := rpcKey{caller: nd.id, msgID: msgID}
+	n.mu.Lock()
+	n.rpcs[key] = nil
+	n.mu.Unlock()
+
+	target.deliver(maelstrom.Message{Src: nd.id, Dest: dest, Body: buf})
+
+	n.mu.Lock()
+	reply := n.rpcs[key]
+	delete(n.rpcs, key)
+	n.mu.Unlock()
+
+	if reply == nil {
+		return maelstrom.Message{}, context.DeadlineExceeded
+	}
+	if rpcErr := reply.RPCError(); rpcErr != nil {
+		return *reply, rpcErr
+	}
+	return *reply, nil
+}
+
+// Errors returns handler errors recorded on this node.
+func (nd *Node) Errors() []error {
+	return slices.Clone(nd.errors)
+}
+
+// deliver runs the handler for msg. Init is handled like Maelstrom does:
+// node IDs are set before the application's init handler runs.
+func (nd *Node) deliver(msg maelstrom.Message) {
+	var head maelstrom.MessageBody
+	if err := json.Unmarshal(msg.Body, &head); err != nil {
+		nd.errors = append(nd.errors, err)
+		return
+	}
+
+	// Replies to RPCs are only consumed by SyncRPC; stray ones are ignored
+	if head.InReplyTo != 0 {
+		return
+	}
+
+	if head.Type == "init" {
+		var init maelstrom.InitMessageBody
+		if err := json.Unmarshal(msg.Body, &init); err != nil {
+			nd.errors = append(nd.errors, err)
+			return
+		}
+		nd.nodeIDs = init.NodeIDs
+	}
+
+	h := nd.handlers[head.Type]
+	if h == nil {
+		if head.Type != "init" {
+			nd.errors = append(nd.errors, fmt.Errorf("no handler for %s", head.Type))
+		}
+		return
+	}
+
+	nd.run(h, msg)
+}
+
+// run executes a handler, replying with an RPC error if it fails, the way
+// *maelstrom.Node does.
+func (nd *Node) run(h maelstrom.HandlerFunc, msg maelstrom.Message) {
+	err := h(msg)
+	if err == nil {
+		return
+	}
+	nd.errors = append(nd.errors, err)
+
+	rpcErr, ok := err.(*maelstrom.RPCError)
+	if !ok {
+		rpcErr = maelstrom.NewRPCError(maelstrom.Crash, err.Error())
+	}
+	nd.Reply(msg, rpcErr)
+}
+
+// withField marshals body to a JSON object and sets key on it.
+func withField(body any, key string, value any) (map[string]any, error) {
+	b := make(map[string]any)
+	buf, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(buf, &b); err != nil {
+		return nil, err
+	}
+	b[key] = value
+	return b, nil
+}
@@ -0,0 +1,163 @@
+package sim
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type ping struct {
+	Type  string `json:"type"`
+	MsgID int    `json:"msg_id,omitempty"`
+	N     int    `json:"n"`
+}
+
+// echoNetwork builds a network whose nodes reply "pong" to every "ping" and
+// count what they receive.
+func echoNetwork(cfg Config, ids ...string) (*Network, map[string]*int) {
+	net := NewNetwork(cfg, ids)
+	counts := make(map[string]*int)
+	for _, id := range ids {
+		node := net.Node(id)
+		c := new(int)
+		counts[id] = c
+		node.Handle("ping", func(msg maelstrom.Message) error {
+			*c++
+			var req ping
+			json.Unmarshal(msg.Body, &req)
+			return node.Reply(msg, ping{Type: "pong", N: req.N})
+		})
+		node.Handle("pong", func(msg maelstrom.Message) error {
+			*c++
+			return nil
+		})
+	}
+	net.Init()
+	return net, counts
+}
+
+func TestNetwork_InitSetsNodeIDs(t *testing.T) {
+	net, _ := echoNetwork(Config{}, "n0", "n1")
+	assert.Equal(t, []string{"n0", "n1"}, net.Node("n1").NodeIDs())
+}
+
+func TestNetwork_Latency(t *testing.T) {
+	net, counts := echoNetwork(Config{Latency: 100 * time.Millisecond}, "n0", "n1")
+
+	require.NoError(t, net.Node("n0").Send("n1", ping{Type: "ping"}))
+	net.RunFor(99 * time.Millisecond)
+	assert.Equal(t, 0, *counts["n1"])
+
+	net.RunFor(time.Millisecond)
+	assert.Equal(t, 1, *counts["n1"])
+
+	// The pong takes another full latency to come back
+	net.RunFor(100 * time.Millisecond)
+	assert.Equal(t, 1, *counts["n0"])
+}
+
+func TestNetwork_ClientReplies(t *testing.T) {
+	net, _ := echoNetwork(Config{Latency: time.Millisecond}, "n0")
+
+	require.NoError(t, net.Inject("c1", "n0", ping{Type: "ping", MsgID: 7, N: 3}))
+	net.RunFor(10 * time.Millisecond)
+
+	replies := net.Replies("c1")
+	require.Len(t, replies, 1)
+	var body struct {
+		Type      string `json:"type"`
+		InReplyTo int    `json:"in_reply_to"`
+		N         int    `json:"n"`
+	}
+	require.NoError(t, json.Unmarshal(replies[0].Body, &body))
+	assert.Equal(t, "pong", body.Type)
+	assert.Equal(t, 7, body.InReplyTo)
+	assert.Equal(t, 3, body.N)
+}
+
+func TestNetwork_PartitionAndHeal(t *testing.T) {
+	net, counts := echoNetwork(Config{Latency: time.Millisecond}, "n0", "n1", "n2")
+	net.Partition([]string{"n0"}, []string{"n1"})
+
+	net.Node("n0").Send("n1", ping{Type: "ping"})
+	net.Node("n0").Send("n2", ping{Type: "ping"})
+	net.RunFor(10 * time.Millisecond)
+	assert.Equal(t, 0, *counts["n1"])
+	assert.Equal(t, 1, *counts["n2"])
+	assert.Equal(t, 1, net.Stats().Blocked)
+
+	net.Heal()
+	net.Node("n0").Send("n1", ping{Type: "ping"})
+	net.RunFor(10 * time.Millisecond)
+	assert.Equal(t, 1, *counts["n1"])
+}
+
+func TestNetwork_DropAndDuplicateAreSeeded(t *testing.T) {
+	run := func() Stats {
+		net, _ := echoNetwork(Config{Seed: 42, Latency: time.Millisecond, DropRate: 0.3, DupRate: 0.2}, "n0", "n1")
+		for i := 0; i < 200; i++ {
+			net.Node("n0").Send("n1", ping{Type: "pong", N: i})
+		}
+		net.RunFor(time.Second)
+		return net.Stats()
+	}
+
+	a, b := run(), run()
+	assert.Equal(t, a, b)
+	assert.Greater(t, a.Dropped, 0)
+	assert.Greater(t, a.Duplicated, 0)
+	assert.Equal(t, a.Sent-a.Dropped+a.Duplicated, a.Delivered)
+}
+
+func TestNetwork_Timers(t *testing.T) {
+	net := NewNetwork(Config{}, nil)
+	var fired []time.Duration
+	start := net.Now()
+	net.Every(30*time.Millisecond, func() {
+		fired = append(fired, net.Now().Sub(start))
+	})
+
+	net.RunFor(100 * time.Millisecond)
+	assert.Equal(t, []time.Duration{30 * time.Millisecond, 60 * time.Millisecond, 90 * time.Millisecond}, fired)
+	assert.Equal(t, 100*time.Millisecond, net.Now().Sub(start))
+}
+
+func TestNetwork_RunUntil(t *testing.T) {
+	net, counts := echoNetwork(Config{Latency: 10 * time.Millisecond}, "n0", "n1")
+	net.Node("n0").Send("n1", ping{Type: "ping"})
+
+	ok := net.RunUntil(func() bool { return *counts["n0"] == 1 }, time.Second)
+	assert.True(t, ok)
+	assert.Equal(t, 20*time.Millisecond, net.Now().Sub(time.Unix(0, 0)))
+}
+
+func TestNode_SyncRPC(t *testing.T) {
+	net, _ := echoNetwork(Config{Latency: 10 * time.Millisecond}, "n0", "n1")
+
+	reply, err := net.Node("n0").SyncRPC(context.Background(), "n1", ping{Type: "ping", N: 5})
+	require.NoError(t, err)
+	var body ping
+	require.NoError(t, json.Unmarshal(reply.Body, &body))
+	assert.Equal(t, "pong", body.Type)
+	assert.Equal(t, 5, body.N)
+
+	net.Partition([]string{"n0"}, []string{"n1"})
+	_, err = net.Node("n0").SyncRPC(context.Background(), "n1", ping{Type: "ping"})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestNode_SyncRPCError(t *testing.T) {
+	net := NewNetwork(Config{}, []string{"n0", "n1"})
+	net.Node("n1").Handle("fail", func(msg maelstrom.Message) error {
+		return maelstrom.NewRPCError(maelstrom.Abort, "nope")
+	})
+	net.Init()
+
+	_, err := net.Node("n0").SyncRPC(context.Background(), "n1", ping{Type: "fail"})
+	assert.Equal(t, maelstrom.Abort, maelstrom.ErrorCode(err))
+}
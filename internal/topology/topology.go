@@ -0,0 +1,196 @@
+// Package topology builds the gossip overlay each node forwards along.
+// Maelstrom's suggested topology is only a hint, so every strategy here
+// derives a node's neighbour set deterministically from the cluster's node
+// list, or for Given from the suggestion every node receives; all nodes
+// compute the same overlay without exchanging messages. Every strategy
+// produces symmetric links: if a neighbours b, b neighbours a.
+package topology
+
+import (
+	// --- Standard Lib ---
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	// --- Internal Lib ---
+	"maelstrom-broadcast/internal/protocol"
+)
+
+// DefaultTreeArity is the branching factor used by "tree" without an explicit k.
+const DefaultTreeArity = 4
+
+// Strategy computes the neighbour set for a node.
+// nodes is the full cluster including self; given is the topology Maelstrom
+// suggested, which may be nil if none has been received.
+type Strategy interface {
+	Neighbors(self string, nodes []string, given protocol.Topology) []string
+}
+
+// Parse returns the strategy with the given name: "given", "mesh", "ring",
+// "tree" or "tree:<k>", "hub" or "grid".
+func Parse(name string) (Strategy, error) {
+	kind, arg, hasArg := strings.Cut(name, ":")
+	switch kind {
+	case "given":
+		return Given{}, nil
+	case "mesh":
+		return Mesh{}, nil
+	case "ring":
+		return Ring{}, nil
+	case "tree":
+		k := DefaultTreeArity
+		if hasArg {
+			n, err := strconv.Atoi(arg)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("topology: invalid tree arity %q", arg)
+			}
+			k = n
+		}
+		return Tree{K: k}, nil
+	case "hub":
+		return Hub{}, nil
+	case "grid":
+		return Grid{}, nil
+	default:
+		return nil, fmt.Errorf("topology: unknown strategy %q", name)
+	}
+}
+
+// Build computes self's neighbours under s, with self removed and duplicates
+// collapsed. The result is sorted so callers see a stable order.
+func Build(s Strategy, self string, nodes []string, given protocol.Topology) []string {
+	seen := make(map[string]struct{})
+	out := make([]string, 0)
+	for _, n := range s.Neighbors(self, sorted(nodes), given) {
+		if n == self {
+			continue
+		}
+		if _, ok := seen[n]; ok {
+			continue
+		}
+		seen[n] = struct{}{}
+		out = append(out, n)
+	}
+	slices.Sort(out)
+	return out
+}
+
+// sorted returns a sorted copy of nodes so every node indexes the cluster
+// identically regardless of the order Maelstrom listed it in.
+func sorted(nodes []string) []string {
+	out := slices.Clone(nodes)
+	slices.Sort(out)
+	return out
+}
+
+// Given uses Maelstrom's suggested topology, falling back to a full mesh
+// until one has been received. A one-way link in the suggestion is used in
+// both directions, so the overlay stays symmetric.
+type Given struct{}
+
+// Neighbors implements Strategy.
+func (Given) Neighbors(self string, nodes []string, given protocol.Topology) []string {
+	if given == nil {
+		return Mesh{}.Neighbors(self, nodes, given)
+	}
+	out := slices.Clone(given[self])
+	for n, peers := range given {
+		if slices.Contains(peers, self) {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+// Mesh connects every node to every other node.
+type Mesh struct{}
+
+// Neighbors implements Strategy.
+func (Mesh) Neighbors(self string, nodes []string, _ protocol.Topology) []string {
+	return nodes
+}
+
+// Ring connects each node to its predecessor and successor.
+type Ring struct{}
+
+// Neighbors implements Strategy.
+func (Ring) Neighbors(self string, nodes []string, _ protocol.Topology) []string {
+	i := slices.Index(nodes, self)
+	if i < 0 || len(nodes) < 2 {
+		return nil
+	}
+	n := len(nodes)
+	return []string{nodes[(i+n-1)%n], nodes[(i+1)%n]}
+}
+
+// Tree arranges nodes in a K-ary spanning tree rooted at the first node,
+// linking each node to its parent and children.
+type Tree struct {
+	K int
+}
+
+// Neighbors implements Strategy.
+func (t Tree) Neighbors(self string, nodes []string, _ protocol.Topology) []string {
+	i := slices.Index(nodes, self)
+	if i < 0 {
+		return nil
+	}
+	k := t.K
+	if k < 1 {
+		k = DefaultTreeArity
+	}
+
+	var out []string
+	if i > 0 {
+		out = append(out, nodes[(i-1)/k])
+	}
+	for c := k*i + 1; c <= k*i+k && c < len(nodes); c++ {
+		out = append(out, nodes[c])
+	}
+	return out
+}
+
+// Hub connects every node to the first node, which relays between them.
+type Hub struct{}
+
+// Neighbors implements Strategy.
+func (Hub) Neighbors(self string, nodes []string, _ protocol.Topology) []string {
+	if len(nodes) == 0 || !slices.Contains(nodes, self) {
+		return nil
+	}
+	if self == nodes[0] {
+		return nodes
+	}
+	return nodes[:1]
+}
+
+// Grid lays nodes out row by row on a square grid and links each node to
+// its horizontal and vertical neighbours.
+type Grid struct{}
+
+// Neighbors implements Strategy.
+func (Grid) Neighbors(self string, nodes []string, _ protocol.Topology) []string {
+	i := slices.Index(nodes, self)
+	if i < 0 {
+		return nil
+	}
+	width := int(math.Ceil(math.Sqrt(float64(len(nodes)))))
+	row, col := i/width, i%width
+
+	var out []string
+	add := func(r, c int) {
+		if r < 0 || c < 0 || c >= width {
+			return
+		}
+		if j := r*width + c; j < len(nodes) {
+			out = append(out, nodes[j])
+		}
+	}
+	add(row-1, col)
+	add(row+1, col)
+	add(row, col-1)
+	add(row, col+1)
+	return out
+}
@@ -0,0 +1,130 @@
+package topology
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+
+	"maelstrom-broadcast/internal/protocol"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func cluster(n int) []string {
+	nodes := make([]string, n)
+	for i := range nodes {
+		nodes[i] = fmt.Sprintf("n%d", i)
+	}
+	return nodes
+}
+
+// overlay builds the neighbour sets for every node in the cluster.
+func overlay(s Strategy, nodes []string) map[string][]string {
+	out := make(map[string][]string, len(nodes))
+	for _, n := range nodes {
+		out[n] = Build(s, n, nodes, nil)
+	}
+	return out
+}
+
+// connected reports whether every node is reachable from the first one.
+func connected(g map[string][]string, nodes []string) bool {
+	seen := map[string]bool{nodes[0]: true}
+	stack := []string{nodes[0]}
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, m := range g[n] {
+			if !seen[m] {
+				seen[m] = true
+				stack = append(stack, m)
+			}
+		}
+	}
+	return len(seen) == len(nodes)
+}
+
+func TestParse(t *testing.T) {
+	for _, name := range []string{"given", "mesh", "ring", "tree", "tree:2", "hub", "grid"} {
+		_, err := Parse(name)
+		assert.NoError(t, err, name)
+	}
+
+	s, err := Parse("tree:3")
+	require.NoError(t, err)
+	assert.Equal(t, Tree{K: 3}, s)
+
+	_, err = Parse("tree:0")
+	assert.Error(t, err)
+	_, err = Parse("star")
+	assert.Error(t, err)
+}
+
+func TestStrategies_SymmetricAndConnected(t *testing.T) {
+	strategies := []Strategy{Mesh{}, Ring{}, Tree{K: 2}, Tree{K: 4}, Hub{}, Grid{}}
+	for _, size := range []int{1, 2, 5, 25} {
+		nodes := cluster(size)
+		for _, s := range strategies {
+			g := overlay(s, nodes)
+			assert.True(t, connected(g, nodes), "%T size %d", s, size)
+			for a, ns := range g {
+				assert.NotContains(t, ns, a, "%T self link", s)
+				for _, b := range ns {
+					assert.Contains(t, g[b], a, "%T %s->%s not symmetric", s, a, b)
+				}
+			}
+		}
+	}
+}
+
+func TestBuild_IndependentOfInputOrder(t *testing.T) {
+	nodes := cluster(10)
+	shuffled := slices.Clone(nodes)
+	slices.Reverse(shuffled)
+
+	for _, s := range []Strategy{Ring{}, Tree{K: 3}, Hub{}, Grid{}} {
+		assert.Equal(t, Build(s, "n4", nodes, nil), Build(s, "n4", shuffled, nil), "%T", s)
+	}
+}
+
+func TestMesh(t *testing.T) {
+	assert.Equal(t, []string{"n0", "n2"}, Build(Mesh{}, "n1", cluster(3), nil))
+}
+
+func TestRing(t *testing.T) {
+	assert.Equal(t, []string{"n1", "n4"}, Build(Ring{}, "n0", cluster(5), nil))
+}
+
+func TestTree(t *testing.T) {
+	nodes := cluster(7)
+	assert.Equal(t, []string{"n1", "n2"}, Build(Tree{K: 2}, "n0", nodes, nil))
+	assert.Equal(t, []string{"n0", "n3", "n4"}, Build(Tree{K: 2}, "n1", nodes, nil))
+	assert.Equal(t, []string{"n2"}, Build(Tree{K: 2}, "n6", nodes, nil))
+}
+
+func TestHub(t *testing.T) {
+	nodes := cluster(4)
+	assert.Equal(t, []string{"n1", "n2", "n3"}, Build(Hub{}, "n0", nodes, nil))
+	assert.Equal(t, []string{"n0"}, Build(Hub{}, "n3", nodes, nil))
+}
+
+func TestGrid(t *testing.T) {
+	// 3x3 layout: n4 sits in the centre
+	nodes := []string{"a", "b", "c", "d", "e", "f", "g", "h", "i"}
+	assert.Equal(t, []string{"b", "d", "f", "h"}, Build(Grid{}, "e", nodes, nil))
+	assert.Equal(t, []string{"b", "d"}, Build(Grid{}, "a", nodes, nil))
+}
+
+func TestGiven(t *testing.T) {
+	given := protocol.Topology{"n0": {"n1"}, "n1": {"n0", "n2"}}
+	assert.Equal(t, []string{"n0", "n2"}, Build(Given{}, "n1", cluster(3), given))
+
+	// Without a suggestion, fall back to a full mesh
+	assert.Equal(t, []string{"n0", "n2"}, Build(Given{}, "n1", cluster(3), nil))
+
+	// One-way links are used both ways
+	oneWay := protocol.Topology{"n0": {"n1", "n2"}, "n1": {}, "n2": {"n1"}}
+	assert.Equal(t, []string{"n0", "n2"}, Build(Given{}, "n1", cluster(3), oneWay))
+	assert.Equal(t, []string{"n0", "n1"}, Build(Given{}, "n2", cluster(3), oneWay))
+}
@@ -0,0 +1,198 @@
+// Package txn implements a totally-available transactional register store
+// for the txn-rw-register workload. Transactions execute atomically against
+// the local replica and their final writes are replicated as a unit, ordered
+// by Lamport timestamps so every replica converges on the same values.
+package txn
+
+import (
+	// --- Standard Lib ---
+	"slices"
+	"sync"
+
+	// --- Internal Lib ---
+	"maelstrom-broadcast/internal/protocol"
+)
+
+// stamp is a Lamport timestamp; ties on clock are broken by node ID.
+type stamp struct {
+	clock uint64
+	node  string
+}
+
+// after reports whether s orders after o.
+func (s stamp) after(o stamp) bool {
+	if s.clock != o.clock {
+		return s.clock > o.clock
+	}
+	return s.node > o.node
+}
+
+// register holds the current value of a key and the stamp that wrote it.
+type register struct {
+	value int
+	stamp stamp
+}
+
+// Store holds the local replica of every register plus a replication log of
+// write sets that peers may not have seen yet.
+type Store struct {
+	mu sync.Mutex
+
+	// node is this replica's ID, used to stamp local transactions
+	node string
+
+	// clock is the Lamport clock, advanced past every stamp observed
+	clock uint64
+
+	// registers maps keys to their current value
+	registers map[int]register
+
+	// log records the write sets applied here, local or remote, in apply
+	// order; peers track how far into it they have acknowledged. log[i] has
+	// index base+i, the ones before base having been acknowledged by every
+	// peer and trimmed
+	log  []protocol.TxnWrites
+	base int
+
+	// seen deduplicates write sets that arrive via several peers
+	seen map[stamp]struct{}
+}
+
+// NewStore creates an empty store for the given node.
+func NewStore(node string) *Store {
+	return &Store{
+		node:      node,
+		registers: make(map[int]register),
+		seen:      make(map[stamp]struct{}),
+	}
+}
+
+// SetNode sets the ID used to stamp local transactions. Maelstrom only
+// assigns node IDs at init, after the store has been constructed.
+func (s *Store) SetNode(node string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.node = node
+}
+
+// Execute runs ops atomically against the local replica and returns them with
+// read values filled in. Reads observe earlier writes in the same transaction.
+// Only the last write per key is replicated, so peers never see intermediate
+// states of a transaction. Writes without a value must be rejected by the
+// caller; they are ignored here.
+func (s *Store) Execute(ops []protocol.TxnOp) []protocol.TxnOp {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.clock++
+	st := stamp{clock: s.clock, node: s.node}
+
+	out := make([]protocol.TxnOp, len(ops))
+	writes := make(map[int]int)
+	for i, op := range ops {
+		out[i] = op
+		switch op.Op {
+		case "r":
+			if v, ok := writes[op.Key]; ok {
+				out[i].Value = &v
+			} else if r, ok := s.registers[op.Key]; ok {
+				v := r.value
+				out[i].Value = &v
+			} else {
+				out[i].Value = nil
+			}
+		case "w":
+			if op.Value != nil {
+				writes[op.Key] = *op.Value
+			}
+		}
+	}
+
+	if len(writes) > 0 {
+		s.apply(st, writes)
+	}
+	return out
+}
+
+// Merge applies write sets replicated from peers. Each set is applied at
+// most once; keys already written by a later stamp keep their value.
+// Returns the number of write sets that were new to this replica.
+func (s *Store) Merge(sets []protocol.TxnWrites) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	applied := 0
+	for _, ws := range sets {
+		st := stamp{clock: ws.Clock, node: ws.Node}
+		if _, ok := s.seen[st]; ok {
+			continue
+		}
+		if ws.Clock > s.clock {
+			s.clock = ws.Clock
+		}
+		s.apply(st, ws.Writes)
+		applied++
+	}
+	return applied
+}
+
+// apply installs writes stamped st and appends them to the replication log.
+// Caller must hold s.mu.
+func (s *Store) apply(st stamp, writes map[int]int) {
+	for k, v := range writes {
+		if cur, ok := s.registers[k]; ok && !st.after(cur.stamp) {
+			continue
+		}
+		s.registers[k] = register{value: v, stamp: st}
+	}
+	s.seen[st] = struct{}{}
+	s.log = append(s.log, protocol.TxnWrites{
+		Clock:  st.clock,
+		Node:   st.node,
+		Writes: writes,
+	})
+}
+
+// Len returns the length of the replication log, trimmed write sets included.
+func (s *Store) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.base + len(s.log)
+}
+
+// Since returns up to limit write sets starting at log index from, along with
+// the index just past the last one returned. Trimmed write sets are skipped.
+func (s *Store) Since(from, limit int) ([]protocol.TxnWrites, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	from = max(from, s.base)
+	if from >= s.base+len(s.log) {
+		return nil, from
+	}
+	end := s.base + len(s.log)
+	if limit > 0 && from+limit < end {
+		end = from + limit
+	}
+	out := make([]protocol.TxnWrites, end-from)
+	copy(out, s.log[from-s.base:end-s.base])
+	return out, end
+}
+
+// Trim drops the write sets before log index upto, which every peer has
+// acknowledged, so the log does not grow forever. Returns how many were
+// dropped.
+func (s *Store) Trim(upto int) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := min(upto, s.base+len(s.log)) - s.base
+	if n <= 0 {
+		return 0
+	}
+	s.log = slices.Clone(s.log[n:])
+	s.base += n
+	return n
+}
@@ -0,0 +1,136 @@
+package txn
+
+import (
+	"testing"
+
+	"maelstrom-broadcast/internal/protocol"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func intPtr(v int) *int {
+	return &v
+}
+
+func read(k int) protocol.TxnOp {
+	return protocol.TxnOp{Op: "r", Key: k}
+}
+
+func write(k, v int) protocol.TxnOp {
+	return protocol.TxnOp{Op: "w", Key: k, Value: intPtr(v)}
+}
+
+func TestStore_ReadMissing(t *testing.T) {
+	s := NewStore("n0")
+
+	out := s.Execute([]protocol.TxnOp{read(1)})
+	require.Len(t, out, 1)
+	assert.Nil(t, out[0].Value)
+	assert.Equal(t, 0, s.Len())
+}
+
+func TestStore_ReadOwnWrites(t *testing.T) {
+	s := NewStore("n0")
+
+	out := s.Execute([]protocol.TxnOp{write(1, 5), read(1), write(1, 6), read(1)})
+	assert.Equal(t, 5, *out[1].Value)
+	assert.Equal(t, 6, *out[3].Value)
+
+	out = s.Execute([]protocol.TxnOp{read(1)})
+	assert.Equal(t, 6, *out[0].Value)
+}
+
+func TestStore_ReplicatesFinalWritesOnly(t *testing.T) {
+	s := NewStore("n0")
+	s.Execute([]protocol.TxnOp{write(1, 5), write(1, 6), write(2, 7)})
+
+	sets, next := s.Since(0, 0)
+	require.Len(t, sets, 1)
+	assert.Equal(t, 1, next)
+	assert.Equal(t, map[int]int{1: 6, 2: 7}, sets[0].Writes)
+}
+
+func TestStore_MergeDeduplicates(t *testing.T) {
+	a, b := NewStore("n0"), NewStore("n1")
+	a.Execute([]protocol.TxnOp{write(1, 1)})
+
+	sets, _ := a.Since(0, 0)
+	assert.Equal(t, 1, b.Merge(sets))
+	assert.Equal(t, 0, b.Merge(sets))
+	assert.Equal(t, 1, b.Len())
+}
+
+func TestStore_ConflictingWritesConverge(t *testing.T) {
+	a, b := NewStore("n0"), NewStore("n1")
+	a.Execute([]protocol.TxnOp{write(1, 10)})
+	b.Execute([]protocol.TxnOp{write(1, 20)})
+
+	fromA, _ := a.Since(0, 0)
+	fromB, _ := b.Since(0, 0)
+	a.Merge(fromB)
+	b.Merge(fromA)
+
+	ra := a.Execute([]protocol.TxnOp{read(1)})
+	rb := b.Execute([]protocol.TxnOp{read(1)})
+	assert.Equal(t, *ra[0].Value, *rb[0].Value)
+	// Equal clocks tie-break on node ID
+	assert.Equal(t, 20, *ra[0].Value)
+}
+
+func TestStore_CausalWriteWins(t *testing.T) {
+	a, b := NewStore("n1"), NewStore("n0")
+	a.Execute([]protocol.TxnOp{write(1, 10)})
+
+	// b observes a's write before overwriting, so its stamp must order later
+	// even though its node ID sorts first
+	sets, _ := a.Since(0, 0)
+	b.Merge(sets)
+	b.Execute([]protocol.TxnOp{write(1, 20)})
+
+	fromB, _ := b.Since(1, 0)
+	a.Merge(fromB)
+	out := a.Execute([]protocol.TxnOp{read(1)})
+	assert.Equal(t, 20, *out[0].Value)
+}
+
+func TestStore_SinceLimit(t *testing.T) {
+	s := NewStore("n0")
+	for i := 0; i < 5; i++ {
+		s.Execute([]protocol.TxnOp{write(i, i)})
+	}
+
+	sets, next := s.Since(1, 2)
+	assert.Len(t, sets, 2)
+	assert.Equal(t, 3, next)
+
+	sets, next = s.Since(5, 2)
+	assert.Empty(t, sets)
+	assert.Equal(t, 5, next)
+}
+
+func TestStore_TrimKeepsIndices(t *testing.T) {
+	s := NewStore("n0")
+	for v := 0; v < 4; v++ {
+		s.Execute([]protocol.TxnOp{write(v, v)})
+	}
+
+	assert.Equal(t, 2, s.Trim(2))
+	assert.Zero(t, s.Trim(1))
+	assert.Equal(t, 4, s.Len())
+
+	sets, next := s.Since(3, 0)
+	require.Len(t, sets, 1)
+	assert.Equal(t, 4, next)
+	assert.Equal(t, map[int]int{3: 3}, sets[0].Writes)
+
+	sets, next = s.Since(0, 1)
+	require.Len(t, sets, 1, "trimmed write sets are skipped")
+	assert.Equal(t, 3, next)
+
+	assert.Equal(t, 2, s.Trim(10))
+	s.Execute([]protocol.TxnOp{write(9, 9)})
+	sets, next = s.Since(4, 0)
+	require.Len(t, sets, 1)
+	assert.Equal(t, 5, next)
+}
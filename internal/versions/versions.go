@@ -0,0 +1,272 @@
+// Package versions tags broadcast values with the node that first learned
+// them and a per-origin sequence number, so what a node holds can be
+// summarised as a version vector: the highest sequence number seen from each
+// origin without a gap. Comparing a peer's vector with the local log gives
+// exactly the values the peer is missing. Entries every neighbour holds can be
+// trimmed; a peer found behind a trimmed prefix is told to skip it and gets
+// the values another way.
+package versions
+
+import (
+	// --- Standard Lib ---
+	"maps"
+	"slices"
+	"sync"
+
+	// --- Internal Lib ---
+	"maelstrom-broadcast/internal/protocol"
+)
+
+// Vector maps each origin to the highest sequence number held from it with
+// no gaps below. A missing origin means nothing is held from it.
+type Vector map[string]uint64
+
+// Merge raises v to cover everything o covers. Reports whether v changed.
+func (v Vector) Merge(o Vector) bool {
+	changed := false
+	for origin, seq := range o {
+		if seq > v[origin] {
+			v[origin] = seq
+			changed = true
+		}
+	}
+	return changed
+}
+
+// Meet lowers v to what both v and o cover. Origins o lacks are dropped.
+func (v Vector) Meet(o Vector) {
+	for origin, seq := range v {
+		if other, ok := o[origin]; !ok || other == 0 {
+			delete(v, origin)
+		} else if other < seq {
+			v[origin] = other
+		}
+	}
+}
+
+// stream holds the entries from one origin: entries up to base have been
+// trimmed or skipped, values[i] has sequence number base+i+1 for the rest of
+// the gap-free prefix, and ahead holds entries past a gap until it fills.
+type stream struct {
+	base   uint64
+	values []int
+	ahead  map[uint64]int
+}
+
+// high returns the highest sequence number held with no gap below.
+func (st *stream) high() uint64 {
+	return st.base + uint64(len(st.values))
+}
+
+// fill moves entries from ahead onto the gap-free prefix while they follow
+// on from it.
+func (st *stream) fill() {
+	for {
+		v, ok := st.ahead[st.high()+1]
+		if !ok {
+			return
+		}
+		delete(st.ahead, st.high()+1)
+		st.values = append(st.values, v)
+	}
+}
+
+// Log records every tagged entry a node holds. It is safe for concurrent use.
+type Log struct {
+	mu sync.RWMutex
+
+	// origin tags values first learned by this node; next is the last
+	// sequence number issued under it
+	origin string
+	next   uint64
+
+	// streams holds the entries from each origin, this node's included
+	streams map[string]*stream
+}
+
+// NewLog creates an empty log.
+func NewLog() *Log {
+	return &Log{streams: make(map[string]*stream)}
+}
+
+// SetOrigin sets the name this node tags new values with. Maelstrom only
+// assigns node IDs at init, after the log has been constructed.
+func (l *Log) SetOrigin(origin string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.origin, l.next = origin, l.high(origin)
+}
+
+// Append tags value as the next entry from this node and records it.
+func (l *Log) Append(value int) protocol.Entry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.next++
+	e := protocol.Entry{Origin: l.origin, Seq: l.next, Value: value}
+	l.add(e)
+	return e
+}
+
+// Add records an entry learned from a peer. Returns false if it was
+// already held or carries no sequence number.
+func (l *Log) Add(e protocol.Entry) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.add(e)
+}
+
+// add records e. Caller must hold l.mu.
+func (l *Log) add(e protocol.Entry) bool {
+	if e.Seq == 0 {
+		return false
+	}
+	st := l.stream(e.Origin)
+	if e.Seq <= st.high() {
+		return false
+	}
+	if e.Seq > st.high()+1 {
+		if _, ok := st.ahead[e.Seq]; ok {
+			return false
+		}
+		if st.ahead == nil {
+			st.ahead = make(map[uint64]int)
+		}
+		st.ahead[e.Seq] = e.Value
+		return true
+	}
+
+	st.values = append(st.values, e.Value)
+	st.fill()
+	return true
+}
+
+// stream returns origin's stream, creating it if needed. Caller must hold
+// l.mu for writing.
+func (l *Log) stream(origin string) *stream {
+	st, ok := l.streams[origin]
+	if !ok {
+		st = &stream{}
+		l.streams[origin] = st
+	}
+	return st
+}
+
+// high returns the gap-free high-water mark for origin. Caller must hold l.mu.
+func (l *Log) high(origin string) uint64 {
+	if st, ok := l.streams[origin]; ok {
+		return st.high()
+	}
+	return 0
+}
+
+// Vector returns the log's current version vector.
+func (l *Log) Vector() Vector {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	v := make(Vector, len(l.streams))
+	for origin, st := range l.streams {
+		if h := st.high(); h > 0 {
+			v[origin] = h
+		}
+	}
+	return v
+}
+
+// Since returns up to limit entries that have is missing, oldest first
+// within each origin and origins in name order; a limit of zero means no
+// limit. Only the gap-free prefix of each origin is offered, so what a peer
+// acknowledges stays gap-free too. Trimmed entries are never offered; see
+// Behind.
+func (l *Log) Since(have Vector, limit int) []protocol.Entry {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	var out []protocol.Entry
+	for _, origin := range slices.Sorted(maps.Keys(l.streams)) {
+		st := l.streams[origin]
+		for seq := max(have[origin], st.base) + 1; seq <= st.high(); seq++ {
+			if limit > 0 && len(out) == limit {
+				return out
+			}
+			out = append(out, protocol.Entry{Origin: origin, Seq: seq, Value: st.values[seq-st.base-1]})
+		}
+	}
+	return out
+}
+
+// Trim drops the entries upto covers, which every peer that could ask for
+// them already holds, so the log does not keep a copy of every value
+// forever. Returns how many entries were dropped.
+func (l *Log) Trim(upto Vector) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	dropped := 0
+	for origin, seq := range upto {
+		st, ok := l.streams[origin]
+		if !ok {
+			continue
+		}
+		seq = min(seq, st.high())
+		if seq <= st.base {
+			continue
+		}
+		n := int(seq - st.base)
+		st.values = slices.Clone(st.values[n:])
+		st.base += uint64(n)
+		dropped += n
+	}
+	return dropped
+}
+
+// Behind returns, for each origin whose trimmed entries have lacks, the
+// sequence number trimmed up to, or nil if have is missing nothing Since
+// can no longer offer.
+func (l *Log) Behind(have Vector) Vector {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	var out Vector
+	for origin, st := range l.streams {
+		if st.base > have[origin] {
+			if out == nil {
+				out = make(Vector)
+			}
+			out[origin] = st.base
+		}
+	}
+	return out
+}
+
+// Skip records the entries from each origin up to the given sequence
+// number as held without their values, for a peer that trimmed them and
+// sends the values untagged instead. Entries waiting past the skipped gap
+// are taken in. Reports whether the vector moved.
+func (l *Log) Skip(upto Vector) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	moved := false
+	for origin, seq := range upto {
+		if origin == l.origin {
+			continue
+		}
+		st := l.stream(origin)
+		if seq <= st.high() {
+			continue
+		}
+		for ahead := range st.ahead {
+			if ahead <= seq {
+				delete(st.ahead, ahead)
+			}
+		}
+		st.base, st.values = seq, nil
+		st.fill()
+		moved = true
+	}
+	return moved
+}
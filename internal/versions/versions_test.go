@@ -0,0 +1,94 @@
+package versions
+
+import (
+	"testing"
+
+	"maelstrom-broadcast/internal/protocol"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLog_AppendTagsLocalValues(t *testing.T) {
+	l := NewLog()
+	l.SetOrigin("n0")
+
+	assert.Equal(t, protocol.Entry{Origin: "n0", Seq: 1, Value: 10}, l.Append(10))
+	assert.Equal(t, protocol.Entry{Origin: "n0", Seq: 2, Value: 7}, l.Append(7))
+	assert.Equal(t, Vector{"n0": 2}, l.Vector())
+}
+
+func TestLog_GapHoldsBackHighWaterMark(t *testing.T) {
+	l := NewLog()
+
+	assert.True(t, l.Add(protocol.Entry{Origin: "n1", Seq: 1, Value: 1}))
+	assert.True(t, l.Add(protocol.Entry{Origin: "n1", Seq: 3, Value: 3}))
+	assert.False(t, l.Add(protocol.Entry{Origin: "n1", Seq: 3, Value: 3}))
+	assert.False(t, l.Add(protocol.Entry{Origin: "n1", Seq: 0, Value: 9}))
+	assert.Equal(t, Vector{"n1": 1}, l.Vector())
+
+	assert.True(t, l.Add(protocol.Entry{Origin: "n1", Seq: 2, Value: 2}))
+	assert.False(t, l.Add(protocol.Entry{Origin: "n1", Seq: 1, Value: 1}))
+	assert.Equal(t, Vector{"n1": 3}, l.Vector())
+}
+
+func TestLog_SinceReturnsOnlyMissing(t *testing.T) {
+	l := NewLog()
+	l.SetOrigin("n0")
+	l.Append(10)
+	l.Append(11)
+	l.Add(protocol.Entry{Origin: "n1", Seq: 1, Value: 20})
+	l.Add(protocol.Entry{Origin: "n1", Seq: 3, Value: 22})
+
+	assert.Equal(t, []protocol.Entry{
+		{Origin: "n0", Seq: 2, Value: 11},
+		{Origin: "n1", Seq: 1, Value: 20},
+	}, l.Since(Vector{"n0": 1}, 0), "entries past a gap are held back")
+	assert.Equal(t, []protocol.Entry{{Origin: "n0", Seq: 1, Value: 10}}, l.Since(nil, 1))
+	assert.Empty(t, l.Since(l.Vector(), 0))
+}
+
+func TestLog_TrimKeepsUnacknowledged(t *testing.T) {
+	l := NewLog()
+	l.SetOrigin("n0")
+	for v := 10; v < 15; v++ {
+		l.Append(v)
+	}
+
+	assert.Equal(t, 3, l.Trim(Vector{"n0": 3, "n9": 4}))
+	assert.Zero(t, l.Trim(Vector{"n0": 2}))
+	assert.Equal(t, Vector{"n0": 5}, l.Vector(), "trimming keeps the high-water mark")
+	assert.Equal(t, []protocol.Entry{{Origin: "n0", Seq: 4, Value: 13}, {Origin: "n0", Seq: 5, Value: 14}}, l.Since(nil, 0))
+	assert.Equal(t, Vector{"n0": 3}, l.Behind(Vector{"n0": 1}))
+	assert.Nil(t, l.Behind(Vector{"n0": 3}))
+
+	assert.Equal(t, 2, l.Trim(Vector{"n0": 9}))
+	assert.Equal(t, protocol.Entry{Origin: "n0", Seq: 6, Value: 15}, l.Append(15))
+}
+
+func TestLog_SkipJumpsTrimmedGap(t *testing.T) {
+	l := NewLog()
+	l.Add(protocol.Entry{Origin: "n1", Seq: 1, Value: 1})
+	l.Add(protocol.Entry{Origin: "n1", Seq: 5, Value: 5})
+	l.Add(protocol.Entry{Origin: "n1", Seq: 7, Value: 7})
+
+	assert.True(t, l.Skip(Vector{"n1": 4}))
+	assert.False(t, l.Skip(Vector{"n1": 3}))
+	assert.Equal(t, Vector{"n1": 5}, l.Vector(), "entries past the gap are taken in")
+	assert.Equal(t, []protocol.Entry{{Origin: "n1", Seq: 5, Value: 5}}, l.Since(Vector{"n1": 2}, 0))
+
+	assert.True(t, l.Add(protocol.Entry{Origin: "n1", Seq: 6, Value: 6}))
+	assert.Equal(t, Vector{"n1": 7}, l.Vector())
+}
+
+func TestVector_Meet(t *testing.T) {
+	v := Vector{"n0": 3, "n1": 1, "n2": 5}
+	v.Meet(Vector{"n0": 2, "n1": 4})
+	assert.Equal(t, Vector{"n0": 2, "n1": 1}, v)
+}
+
+func TestVector_Merge(t *testing.T) {
+	v := Vector{"n0": 3, "n1": 1}
+	assert.True(t, v.Merge(Vector{"n0": 2, "n1": 4, "n2": 1}))
+	assert.Equal(t, Vector{"n0": 3, "n1": 4, "n2": 1}, v)
+	assert.False(t, v.Merge(Vector{"n0": 3, "n1": 2}))
+}
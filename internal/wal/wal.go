@@ -0,0 +1,309 @@
+// Package wal persists a node's broadcast values and ID reservations in an
+// append-only write-ahead log, so a crashed and restarted node remembers
+// what it has seen and never reissues an ID.
+//
+// A log directory holds two files: snapshot.json, the state as of the last
+// compaction, and wal.log, the fixed-size records appended since. Recovery
+// loads the snapshot and applies the log on top; a torn record at the tail,
+// left by a crash mid-write, is discarded.
+package wal
+
+import (
+	// --- Standard Lib ---
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const (
+	logName      = "wal.log"
+	snapshotName = "snapshot.json"
+
+	// recordSize is one op byte, an 8-byte value and a 4-byte CRC
+	recordSize = 1 + 8 + 4
+)
+
+// Op identifies what a record changes.
+type Op uint8
+
+const (
+	// OpMessage records a broadcast value the node has seen
+	OpMessage Op = iota + 1
+
+	// OpReserveIDs records that counter IDs up to Value may be issued
+	OpReserveIDs
+)
+
+// Record is one logged change.
+type Record struct {
+	Op    Op
+	Value int64
+}
+
+// State is everything a node recovers from its log.
+type State struct {
+	// Messages holds every broadcast value the node had seen
+	Messages []int `json:"messages"`
+
+	// IDLimit is the highest counter ID that may have been issued
+	IDLimit uint64 `json:"id_limit"`
+}
+
+// apply folds a record into the state.
+func (st *State) apply(r Record) {
+	switch r.Op {
+	case OpMessage:
+		st.Messages = append(st.Messages, int(r.Value))
+	case OpReserveIDs:
+		st.IDLimit = max(st.IDLimit, uint64(r.Value))
+	}
+}
+
+// Log is an open write-ahead log. Safe for concurrent use.
+type Log struct {
+	dir string
+
+	// mu guards f, buf, written and pending
+	mu  sync.Mutex
+	f   *os.File
+	buf *bufio.Writer
+
+	// written counts records handed to buf; pending counts those since the
+	// last compaction
+	written uint64
+	pending int
+
+	// syncMU serializes fsyncs; durable is the written count the last
+	// fsync covered
+	syncMU  sync.Mutex
+	durable uint64
+
+	recovered State
+}
+
+// Open opens or creates the log in dir and recovers its state.
+func Open(dir string) (*Log, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	var st State
+	if b, err := os.ReadFile(filepath.Join(dir, snapshotName)); err == nil {
+		if err := json.Unmarshal(b, &st); err != nil {
+			return nil, fmt.Errorf("wal: decode snapshot: %w", err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(filepath.Join(dir, logName), os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	n, err := replay(f, &st)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	// Drop any torn tail so new records follow the last good one
+	if err := f.Truncate(int64(n) * recordSize); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if _, err := f.Seek(int64(n)*recordSize, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return &Log{
+		dir:       dir,
+		f:         f,
+		buf:       bufio.NewWriter(f),
+		pending:   n,
+		recovered: st,
+	}, nil
+}
+
+// replay applies every intact record in r to st and returns how many there were.
+func replay(r io.Reader, st *State) (int, error) {
+	br := bufio.NewReader(r)
+	var rec [recordSize]byte
+	n := 0
+	for {
+		if _, err := io.ReadFull(br, rec[:]); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return n, nil
+			}
+			return n, err
+		}
+		r, ok := decode(rec)
+		if !ok {
+			return n, nil
+		}
+		st.apply(r)
+		n++
+	}
+}
+
+// Recovered returns the state found when the log was opened.
+func (l *Log) Recovered() State {
+	return l.recovered
+}
+
+// Append writes records and returns once they are on disk.
+// Concurrent callers share fsyncs: whoever syncs first makes every record
+// written so far durable, and the others find their records already covered.
+func (l *Log) Append(recs ...Record) error {
+	l.mu.Lock()
+	for _, r := range recs {
+		b := encode(r)
+		if _, err := l.buf.Write(b[:]); err != nil {
+			l.mu.Unlock()
+			return err
+		}
+	}
+	l.written += uint64(len(recs))
+	l.pending += len(recs)
+	mine := l.written
+	l.mu.Unlock()
+
+	return l.sync(mine)
+}
+
+// sync makes every record up to upto durable.
+func (l *Log) sync(upto uint64) error {
+	l.syncMU.Lock()
+	defer l.syncMU.Unlock()
+
+	if l.durable >= upto {
+		return nil
+	}
+
+	l.mu.Lock()
+	err := l.buf.Flush()
+	target := l.written
+	l.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if err := l.f.Sync(); err != nil {
+		return err
+	}
+	l.durable = target
+	return nil
+}
+
+// Pending returns how many records have been appended since the last compaction.
+func (l *Log) Pending() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.pending
+}
+
+// Compact replaces the log with a snapshot of the state returned by build.
+// Appends are held off while build runs and the snapshot is written, so the
+// snapshot covers every record it replaces; build must therefore not append.
+func (l *Log) Compact(build func() State) error {
+	l.syncMU.Lock()
+	defer l.syncMU.Unlock()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, err := json.Marshal(build())
+	if err != nil {
+		return err
+	}
+	if err := writeFileSync(filepath.Join(l.dir, snapshotName), b); err != nil {
+		return err
+	}
+
+	// Records still buffered are covered by the snapshot
+	l.buf.Reset(l.f)
+	if err := l.f.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := l.f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if err := l.f.Sync(); err != nil {
+		return err
+	}
+	l.durable = l.written
+	l.pending = 0
+	return nil
+}
+
+// Close flushes and closes the log.
+func (l *Log) Close() error {
+	l.syncMU.Lock()
+	defer l.syncMU.Unlock()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := l.buf.Flush(); err != nil {
+		l.f.Close()
+		return err
+	}
+	if err := l.f.Sync(); err != nil {
+		l.f.Close()
+		return err
+	}
+	return l.f.Close()
+}
+
+// writeFileSync atomically replaces path with data: it writes a temporary
+// file, fsyncs it, renames it into place and fsyncs the directory.
+func writeFileSync(path string, data []byte) error {
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+
+	d, err := os.Open(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// encode lays a record out as op, big-endian value, CRC of both.
+func encode(r Record) [recordSize]byte {
+	var b [recordSize]byte
+	b[0] = byte(r.Op)
+	binary.BigEndian.PutUint64(b[1:9], uint64(r.Value))
+	binary.BigEndian.PutUint32(b[9:], crc32.ChecksumIEEE(b[:9]))
+	return b
+}
+
+// decode reverses encode, reporting false if the checksum does not match.
+func decode(b [recordSize]byte) (Record, bool) {
+	if crc32.ChecksumIEEE(b[:9]) != binary.BigEndian.Uint32(b[9:]) {
+		return Record{}, false
+	}
+	return Record{Op: Op(b[0]), Value: int64(binary.BigEndian.Uint64(b[1:9]))}, true
+}
@@ -0,0 +1,150 @@
+package wal
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLog_RecoversAppendedRecords(t *testing.T) {
+	dir := t.TempDir()
+
+	l, err := Open(dir)
+	require.NoError(t, err)
+	assert.Empty(t, l.Recovered().Messages)
+
+	require.NoError(t, l.Append(Record{OpMessage, 1}, Record{OpMessage, -7}))
+	require.NoError(t, l.Append(Record{OpReserveIDs, 100}, Record{OpReserveIDs, 50}))
+	require.NoError(t, l.Close())
+
+	l, err = Open(dir)
+	require.NoError(t, err)
+	defer l.Close()
+
+	st := l.Recovered()
+	assert.Equal(t, []int{1, -7}, st.Messages)
+	assert.Equal(t, uint64(100), st.IDLimit)
+	assert.Equal(t, 4, l.Pending())
+}
+
+func TestLog_DiscardsTornTail(t *testing.T) {
+	dir := t.TempDir()
+
+	l, err := Open(dir)
+	require.NoError(t, err)
+	require.NoError(t, l.Append(Record{OpMessage, 1}, Record{OpMessage, 2}))
+	require.NoError(t, l.Close())
+
+	// Simulate a crash halfway through writing a third record
+	f, err := os.OpenFile(filepath.Join(dir, logName), os.O_APPEND|os.O_WRONLY, 0)
+	require.NoError(t, err)
+	b := encode(Record{OpMessage, 3})
+	_, err = f.Write(b[:5])
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	l, err = Open(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 2}, l.Recovered().Messages)
+
+	// New records follow the last good one
+	require.NoError(t, l.Append(Record{OpMessage, 4}))
+	require.NoError(t, l.Close())
+
+	l, err = Open(dir)
+	require.NoError(t, err)
+	defer l.Close()
+	assert.Equal(t, []int{1, 2, 4}, l.Recovered().Messages)
+}
+
+func TestLog_DiscardsCorruptRecord(t *testing.T) {
+	dir := t.TempDir()
+
+	l, err := Open(dir)
+	require.NoError(t, err)
+	require.NoError(t, l.Append(Record{OpMessage, 1}, Record{OpMessage, 2}))
+	require.NoError(t, l.Close())
+
+	path := filepath.Join(dir, logName)
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	b[recordSize+3] ^= 0xff
+	require.NoError(t, os.WriteFile(path, b, 0o644))
+
+	l, err = Open(dir)
+	require.NoError(t, err)
+	defer l.Close()
+	assert.Equal(t, []int{1}, l.Recovered().Messages)
+}
+
+func TestLog_CompactReplacesLogWithSnapshot(t *testing.T) {
+	dir := t.TempDir()
+
+	l, err := Open(dir)
+	require.NoError(t, err)
+	require.NoError(t, l.Append(Record{OpMessage, 1}, Record{OpMessage, 2}, Record{OpReserveIDs, 10}))
+
+	require.NoError(t, l.Compact(func() State {
+		return State{Messages: []int{1, 2}, IDLimit: 10}
+	}))
+	assert.Equal(t, 0, l.Pending())
+
+	info, err := os.Stat(filepath.Join(dir, logName))
+	require.NoError(t, err)
+	assert.Zero(t, info.Size())
+
+	require.NoError(t, l.Append(Record{OpMessage, 3}))
+	require.NoError(t, l.Close())
+
+	l, err = Open(dir)
+	require.NoError(t, err)
+	defer l.Close()
+
+	st := l.Recovered()
+	assert.Equal(t, []int{1, 2, 3}, st.Messages)
+	assert.Equal(t, uint64(10), st.IDLimit)
+	assert.Equal(t, 1, l.Pending())
+}
+
+func TestLog_ConcurrentAppends(t *testing.T) {
+	dir := t.TempDir()
+
+	l, err := Open(dir)
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				assert.NoError(t, l.Append(Record{OpMessage, int64(w*100 + i)}))
+			}
+		}()
+	}
+	wg.Wait()
+	require.NoError(t, l.Close())
+
+	l, err = Open(dir)
+	require.NoError(t, err)
+	defer l.Close()
+
+	got := l.Recovered().Messages
+	slices.Sort(got)
+	assert.Len(t, got, 400)
+	assert.Equal(t, 0, got[0])
+	assert.Equal(t, 749, got[len(got)-1])
+}
+
+func TestEncodeDecode(t *testing.T) {
+	for _, r := range []Record{{OpMessage, 0}, {OpMessage, -1}, {OpReserveIDs, 1 << 62}} {
+		got, ok := decode(encode(r))
+		assert.True(t, ok)
+		assert.Equal(t, r, got)
+	}
+}